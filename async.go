@@ -2,25 +2,52 @@ package log
 
 import (
 	"io"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"unsafe"
 )
 
 // AsyncWriter is an Writer that writes asynchronously.
 type AsyncWriter struct {
 	// ChannelSize is the size of the data channel, the default size is 1.
+	// In sharded mode it is the capacity of each shard, rounded up to a power of two
+	// and at least 64.
 	ChannelSize uint
 
+	// Shards specifies the number of lock-free queues the producers are spread over
+	// by the P they run on. A negative value uses GOMAXPROCS, zero uses a single channel.
+	Shards int
+
+	// StrictOrder determines if a sharded writer writes entries in the exact order
+	// they were queued, at the cost of a shared sequence counter.
+	// Otherwise entries are merged approximately in time order.
+	StrictOrder bool
+
 	// Writer specifies the writer of output.
 	Writer Writer
 
 	once    sync.Once
 	ch      chan *Entry
 	chClose chan error
+
+	shards   []asyncShard
+	seq      uint64
+	sleeping uint32
+	closed   uint32
+	wake     chan struct{}
 }
 
 // Close implements io.Closer, and closes the underlying Writer.
 func (w *AsyncWriter) Close() (err error) {
-	w.ch <- nil
+	w.once.Do(w.init)
+	if w.shards != nil {
+		atomic.StoreUint32(&w.closed, 1)
+		w.notify()
+	} else {
+		w.ch <- nil
+	}
 	err = <-w.chClose
 	if closer, ok := w.Writer.(io.Closer); ok {
 		if err1 := closer.Close(); err1 != nil {
@@ -32,10 +59,31 @@ func (w *AsyncWriter) Close() (err error) {
 
 // WriteEntry implements Writer.
 func (w *AsyncWriter) WriteEntry(e *Entry) (int, error) {
-	w.once.Do(func() {
-		// channels
+	w.once.Do(w.init)
+
+	// cheating to logger pool
+	entry := epool.Get().(*Entry)
+	entry.Level = e.Level
+	entry.buf, e.buf = e.buf, entry.buf
+	n := len(entry.buf)
+
+	if w.shards != nil {
+		w.enqueue(entry)
+	} else {
+		w.ch <- entry
+	}
+	return n, nil
+}
+
+func (w *AsyncWriter) init() {
+	w.chClose = make(chan error)
+
+	shards := w.Shards
+	if shards < 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	if shards == 0 {
 		w.ch = make(chan *Entry, w.ChannelSize)
-		w.chClose = make(chan error)
 		go func() {
 			var err error
 			for entry := range w.ch {
@@ -47,15 +95,187 @@ func (w *AsyncWriter) WriteEntry(e *Entry) (int, error) {
 			}
 			w.chClose <- err
 		}()
-	})
+		return
+	}
 
-	// cheating to logger pool
-	entry := epool.Get().(*Entry)
-	entry.Level = e.Level
-	entry.buf, e.buf = e.buf, entry.buf
+	size := uint64(64)
+	for size < uint64(w.ChannelSize) {
+		size <<= 1
+	}
+	w.shards = make([]asyncShard, shards)
+	for i := range w.shards {
+		w.shards[i].slots = make([]asyncSlot, size)
+		for j := range w.shards[i].slots {
+			w.shards[i].slots[j].seq = uint64(j)
+		}
+		w.shards[i].mask = size - 1
+	}
+	w.wake = make(chan struct{}, 1)
+	go w.consume()
+}
+
+// asyncSlot is a cell of the bounded queue of Dmitry Vyukov, see
+// https://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+type asyncSlot struct {
+	seq   uint64
+	key   uint64
+	entry *Entry
+	_     [32 - 16 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+type asyncShard struct {
+	head  uint64
+	_     [56]byte
+	tail  uint64
+	mask  uint64
+	slots []asyncSlot
+	_     [32]byte
+}
+
+func (w *AsyncWriter) enqueue(entry *Entry) {
+	pid := procPin()
+	procUnpin()
+	shard := &w.shards[uint(pid)%uint(len(w.shards))]
+
+	for {
+		pos := atomic.LoadUint64(&shard.head)
+		slot := &shard.slots[pos&shard.mask]
+		switch diff := int64(atomic.LoadUint64(&slot.seq) - pos); {
+		case diff == 0:
+			if !atomic.CompareAndSwapUint64(&shard.head, pos, pos+1) {
+				continue
+			}
+			if w.StrictOrder {
+				slot.key = atomic.AddUint64(&w.seq, 1) - 1
+			} else {
+				_, _, mono := now()
+				slot.key = uint64(mono)
+			}
+			slot.entry = entry
+			atomic.StoreUint64(&slot.seq, pos+1)
+			if atomic.LoadUint32(&w.sleeping) == 1 && atomic.CompareAndSwapUint32(&w.sleeping, 1, 0) {
+				w.notify()
+			}
+			return
+		case diff < 0:
+			// the shard is full, wait for the consumer
+			w.notify()
+			runtime.Gosched()
+		}
+	}
+}
 
-	w.ch <- entry
-	return len(entry.buf), nil
+func (w *AsyncWriter) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dequeue appends the published entries of the shard to items, it is only called by the consumer.
+func (shard *asyncShard) dequeue(items asyncItems) asyncItems {
+	for {
+		slot := &shard.slots[shard.tail&shard.mask]
+		if atomic.LoadUint64(&slot.seq) != shard.tail+1 {
+			return items
+		}
+		items = append(items, asyncItem{slot.key, slot.entry})
+		slot.entry = nil
+		atomic.StoreUint64(&slot.seq, shard.tail+shard.mask+1)
+		shard.tail++
+	}
+}
+
+// drained reports whether every claimed slot of the shard has been dequeued, it is only called by the consumer.
+func (shard *asyncShard) drained() bool {
+	return atomic.LoadUint64(&shard.head) == shard.tail
+}
+
+type asyncItem struct {
+	key   uint64
+	entry *Entry
+}
+
+type asyncItems []asyncItem
+
+func (items asyncItems) Len() int           { return len(items) }
+func (items asyncItems) Less(i, j int) bool { return items[i].key < items[j].key }
+func (items asyncItems) Swap(i, j int)      { items[i], items[j] = items[j], items[i] }
+
+const asyncSpins = 64
+
+// consume merges the shards into the underlying writer until the writer is closed.
+func (w *AsyncWriter) consume() {
+	var err error
+	var items asyncItems
+	var next uint64
+	var idle int
+
+	for {
+		closed := atomic.LoadUint32(&w.closed) == 1
+
+		n := len(items)
+		for i := range w.shards {
+			items = w.shards[i].dequeue(items)
+		}
+		if len(items) == n {
+			if n == 0 && closed && w.drained() {
+				break
+			}
+			// spin a while before parking, waking up costs the producers a channel send.
+			if idle++; idle < asyncSpins {
+				runtime.Gosched()
+				continue
+			}
+			idle = 0
+			// re-check the shards after announcing sleeping, so that no wakeup is lost.
+			atomic.StoreUint32(&w.sleeping, 1)
+			for i := range w.shards {
+				items = w.shards[i].dequeue(items)
+			}
+			if len(items) == n && !closed {
+				<-w.wake
+			}
+			atomic.StoreUint32(&w.sleeping, 0)
+			if len(items) == n {
+				if closed {
+					// a claimed slot is about to be published.
+					runtime.Gosched()
+				}
+				continue
+			}
+		}
+
+		idle = 0
+		sort.Sort(items)
+
+		// in strict order only the contiguous prefix of the sequence is written.
+		i := 0
+		for ; i < len(items); i++ {
+			if w.StrictOrder {
+				if items[i].key != next {
+					break
+				}
+				next++
+			}
+			_, err = w.Writer.WriteEntry(items[i].entry)
+			epool.Put(items[i].entry)
+			items[i].entry = nil
+		}
+		items = items[:copy(items, items[i:])]
+	}
+
+	w.chClose <- err
+}
+
+// drained reports whether the producers have published every claimed slot and all are dequeued.
+func (w *AsyncWriter) drained() bool {
+	for i := range w.shards {
+		if !w.shards[i].drained() {
+			return false
+		}
+	}
+	return true
 }
 
 var _ Writer = (*AsyncWriter)(nil)
@@ -1,9 +1,17 @@
 package log
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestAsyncWriterZero(t *testing.T) {
@@ -32,6 +40,126 @@ func TestAsyncWriterSmall(t *testing.T) {
 	}
 }
 
+func TestAsyncWriterSharded(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := &AsyncWriter{
+			Shards:      4,
+			StrictOrder: strict,
+			Writer:      IOWriter{&buf},
+		}
+
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					_, _ = wlprintf(w, InfoLevel, "%d %d\n", g, i)
+				}
+			}(g)
+		}
+		wg.Wait()
+		if err := w.Close(); err != nil {
+			t.Errorf("async close error: %+v", err)
+		}
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != 8000 {
+			t.Fatalf("async sharded writer strict=%v lost entries: %d", strict, len(lines))
+		}
+		// every entry is written exactly once
+		seen := make(map[string]int)
+		for _, line := range lines {
+			seen[line]++
+		}
+		for g := 0; g < 8; g++ {
+			for i := 0; i < 1000; i++ {
+				if line := fmt.Sprintf("%d %d", g, i); seen[line] != 1 {
+					t.Fatalf("async sharded writer strict=%v wrote %q %d times", strict, line, seen[line])
+				}
+			}
+		}
+		if !strict {
+			continue
+		}
+		// entries of the same producer keep their order in strict mode
+		last := make(map[string]int)
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			i, _ := strconv.Atoi(fields[1])
+			if prev, ok := last[fields[0]]; ok && i != prev+1 {
+				t.Fatalf("async sharded writer reordered producer %s: %d after %d", fields[0], i, prev)
+			}
+			last[fields[0]] = i
+		}
+	}
+}
+
+func TestAsyncWriterShardedStrict(t *testing.T) {
+	var buf bytes.Buffer
+	w := &AsyncWriter{
+		ChannelSize: 1,
+		Shards:      -1,
+		StrictOrder: true,
+		Writer:      IOWriter{&buf},
+	}
+	for i := 0; i < 500; i++ {
+		_, _ = wlprintf(w, InfoLevel, "%d\n", i)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("async close error: %+v", err)
+	}
+
+	var want strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&want, "%d\n", i)
+	}
+	if got := buf.String(); got != want.String() {
+		t.Errorf("async strict writer out of order: %s", got)
+	}
+}
+
+func TestAsyncWriterCloseClaimed(t *testing.T) {
+	var buf bytes.Buffer
+	w := &AsyncWriter{Shards: 1, Writer: IOWriter{&buf}}
+	w.once.Do(w.init)
+
+	// claim a slot as a producer does, without publishing it yet
+	shard := &w.shards[0]
+	atomic.AddUint64(&shard.head, 1)
+
+	done := make(chan error)
+	go func() { done <- w.Close() }()
+
+	select {
+	case <-done:
+		t.Fatalf("async close returned before the claimed slot is published")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	entry := epool.Get().(*Entry)
+	entry.buf = append(entry.buf[:0], "claimed\n"...)
+	shard.slots[0].entry = entry
+	atomic.StoreUint64(&shard.slots[0].seq, 1)
+
+	if err := <-done; err != nil {
+		t.Errorf("async close error: %+v", err)
+	}
+	if got := buf.String(); got != "claimed\n" {
+		t.Errorf("async close lost the claimed entry: %q", got)
+	}
+}
+
+func TestAsyncWriterCloseUnused(t *testing.T) {
+	for _, shards := range []int{0, 2} {
+		w := &AsyncWriter{Shards: shards, Writer: IOWriter{io.Discard}}
+		if err := w.Close(); err != nil {
+			t.Errorf("async close error: %+v", err)
+		}
+	}
+}
+
 func BenchmarkAsyncWriter(b *testing.B) {
 	logger := Logger{
 		Writer: &AsyncWriter{
@@ -48,3 +176,32 @@ func BenchmarkAsyncWriter(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkAsyncWriterContention(b *testing.B) {
+	for _, procs := range []int{1, 4, 16, 64} {
+		for _, shards := range []int{0, -1} {
+			name := fmt.Sprintf("procs=%d/channel", procs)
+			if shards != 0 {
+				name = fmt.Sprintf("procs=%d/sharded", procs)
+			}
+			b.Run(name, func(b *testing.B) {
+				defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+				w := &AsyncWriter{
+					ChannelSize: 1024,
+					Shards:      shards,
+					Writer:      IOWriter{io.Discard},
+				}
+				logger := Logger{Writer: w}
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(b *testing.PB) {
+					for b.Next() {
+						logger.Info().Msg("hello async writer")
+					}
+				})
+				b.StopTimer()
+				_ = w.Close()
+			})
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"os"
+)
+
+// getenv looks up JOURNAL_STREAM and the variables of the XID identity sources.
+var getenv = os.Getenv
+
+// AutoWriter returns a writer suited to where stderr goes. It is a JournalWriter
+// if stderr is connected to the journal, e.g. a systemd service, a ConsoleWriter
+// if stderr is a terminal, otherwise an IOWriter writing JSON to stderr.
+func AutoWriter() Writer {
+	if IsJournalStream(os.Stderr.Fd()) {
+		if w := journalWriter(); w != nil {
+			return w
+		}
+	}
+	if IsTerminal(os.Stderr.Fd()) {
+		return &ConsoleWriter{ColorOutput: true}
+	}
+	return IOWriter{os.Stderr}
+}
+
+// UseAutoWriter sets the writer of the logger to AutoWriter, the DefaultLogger if logger is nil.
+func UseAutoWriter(logger *Logger) {
+	if logger == nil {
+		logger = &DefaultLogger
+	}
+	logger.Writer = AutoWriter()
+}
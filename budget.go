@@ -0,0 +1,217 @@
+package log
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Budget caps the total log volume of the loggers sharing it, in entries or bytes per second.
+//
+// Once the budget runs low, the lowest levels are shed first, each level keeping a reserve of
+// the burst for the levels above it. Entries at error level or above are always admitted.
+// The decision is made before an entry is encoded, by an atomic token bucket.
+//
+// To cap a logger and all the loggers derived from it, set Logger.Budget:
+//
+//	logger := log.Logger{Budget: &log.Budget{Rate: 1000}}
+//	reqLogger := logger
+//	reqLogger.Context = log.NewContext(nil).Str("request_id", id).Value()
+//	reqLogger.Info().Msg("shares the 1000 entries per second of logger")
+type Budget struct {
+	// Rate specifies the number of entries, or bytes if Bytes is true, admitted per second.
+	// The zero value admits nothing below error level, a Budget must set a positive Rate.
+	Rate int64
+
+	// Burst specifies the capacity of the bucket, the default is Rate.
+	Burst int64
+
+	// Bytes determines if the budget counts the bytes of entries instead of entries.
+	// The bytes of an admitted entry are charged after it is encoded.
+	Bytes bool
+
+	// Reserves optionally overrides the fraction of Burst kept for the levels above a level,
+	// the defaults are trace 0.5, debug 0.3, info 0.1 and warn 0.
+	Reserves map[Level]float64
+
+	// SummaryInterval specifies the minimum interval between the summary entries of shed counts, the default is 1 minute.
+	// A summary is written at warn level by the next logger using the budget after the interval, if any entry has been shed.
+	// If SummaryInterval is negative, no summary entries are written.
+	SummaryInterval time.Duration
+
+	once     sync.Once
+	tokens   int64
+	last     int64
+	summary  int64
+	burst    int64
+	reserves [noLevel + 1]int64
+	shed     [noLevel + 1]uint64
+}
+
+var defaultBudgetReserves = map[Level]float64{
+	TraceLevel: 0.5,
+	DebugLevel: 0.3,
+	InfoLevel:  0.1,
+}
+
+func (b *Budget) init() {
+	b.burst = b.Burst
+	if b.burst <= 0 {
+		b.burst = b.Rate
+	}
+	reserves := b.Reserves
+	if reserves == nil {
+		reserves = defaultBudgetReserves
+	}
+	for level := range b.reserves {
+		b.reserves[level] = int64(reserves[Level(level)] * float64(b.burst))
+	}
+
+	now := timeNow().UnixNano()
+	b.tokens = b.burst
+	b.last = now
+	b.summary = now
+}
+
+// Allow reports whether an entry of the level is admitted, taking its token if counting entries.
+// The shed entries are counted for the summary.
+func (b *Budget) Allow(level Level) bool {
+	ok, _ := b.allow(level)
+	return ok
+}
+
+func (b *Budget) allow(level Level) (bool, int64) {
+	b.once.Do(b.init)
+	if level > noLevel {
+		level = noLevel
+	}
+
+	now := b.refill()
+	cost := int64(1)
+	if b.Bytes {
+		cost = 0
+	}
+
+	if level >= ErrorLevel && level != noLevel {
+		atomic.AddInt64(&b.tokens, -cost)
+		return true, now
+	}
+
+	reserve := b.reserves[level]
+	for {
+		tokens := atomic.LoadInt64(&b.tokens)
+		if tokens <= 0 || tokens-cost < reserve {
+			atomic.AddUint64(&b.shed[level], 1)
+			return false, now
+		}
+		if atomic.CompareAndSwapInt64(&b.tokens, tokens, tokens-cost) {
+			return true, now
+		}
+	}
+}
+
+// refill adds the tokens accrued since the last refill, it returns the current time.
+func (b *Budget) refill() int64 {
+	now := timeNow().UnixNano()
+	prev := atomic.LoadInt64(&b.last)
+	if b.Rate <= 0 || now <= prev {
+		return now
+	}
+
+	// an idle bucket accrues no more than a full burst
+	last, elapsed := prev, now-prev
+	if limit := int64(time.Second) * (b.burst/b.Rate + 1); elapsed > limit {
+		last, elapsed = now-limit, limit
+	}
+	// advance the refill time by whole tokens only, so that frequent refills lose nothing
+	n := mulDiv(elapsed, b.Rate, int64(time.Second))
+	if n <= 0 || !atomic.CompareAndSwapInt64(&b.last, prev, last+mulDiv(n, int64(time.Second), b.Rate)) {
+		return now
+	}
+
+	for {
+		tokens := atomic.LoadInt64(&b.tokens)
+		v := tokens + n
+		if v > b.burst {
+			v = b.burst
+		}
+		if v <= tokens || atomic.CompareAndSwapInt64(&b.tokens, tokens, v) {
+			return now
+		}
+	}
+}
+
+// mulDiv returns a*b/c of non-negative numbers without overflowing the intermediate product.
+func mulDiv(a, b, c int64) int64 {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi >= uint64(c) {
+		return math.MaxInt64
+	}
+	q, _ := bits.Div64(hi, lo, uint64(c))
+	if q > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(q)
+}
+
+// charge takes the tokens of an encoded entry of n bytes.
+func (b *Budget) charge(n int) {
+	if b.Bytes {
+		atomic.AddInt64(&b.tokens, -int64(n))
+	}
+}
+
+// Shed returns the numbers of entries shed by level since the last summary.
+func (b *Budget) Shed() map[Level]uint64 {
+	shed := make(map[Level]uint64)
+	for level := range b.shed {
+		if n := atomic.LoadUint64(&b.shed[level]); n != 0 {
+			shed[Level(level)] = n
+		}
+	}
+	return shed
+}
+
+// summarize writes a summary entry of the shed counts by the logger, if the summary interval has elapsed.
+func (b *Budget) summarize(l *Logger, now int64) {
+	interval := b.SummaryInterval
+	switch {
+	case interval < 0:
+		return
+	case interval == 0:
+		interval = time.Minute
+	}
+	last := atomic.LoadInt64(&b.summary)
+	if now-last < int64(interval) {
+		return
+	}
+	shedding := false
+	for level := range b.shed {
+		shedding = shedding || atomic.LoadUint64(&b.shed[level]) != 0
+	}
+	if !shedding || !atomic.CompareAndSwapInt64(&b.summary, last, now) {
+		return
+	}
+
+	var shed [noLevel + 1]uint64
+	var total uint64
+	for level := range b.shed {
+		shed[level] = atomic.SwapUint64(&b.shed[level], 0)
+		total += shed[level]
+	}
+
+	ctx := NewContext(nil)
+	for level, n := range shed {
+		if n != 0 {
+			ctx.Uint64(Level(level).String(), n)
+		}
+	}
+	// the summary is not an entry of the call that crossed the interval
+	logger := *l
+	logger.Context, logger.Caller = nil, 0
+	e := logger.header(WarnLevel)
+	e.budget = nil
+	e.Dict("shed", ctx.Value()).Uint64("shed_total", total).Dur("interval", time.Duration(now-last)).Msg("log budget exceeded")
+}
@@ -0,0 +1,172 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBudgetShedding(t *testing.T) {
+	now := time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	b := &Budget{Rate: 10}
+
+	var admitted int
+	for b.Allow(InfoLevel) {
+		admitted++
+	}
+	if admitted != 9 {
+		t.Errorf("budget admitted %d info entries, want 9 keeping a reserve of 1", admitted)
+	}
+	if !b.Allow(WarnLevel) || b.Allow(WarnLevel) {
+		t.Errorf("budget should admit the reserved warn entry only")
+	}
+	if b.Allow(TraceLevel) {
+		t.Errorf("budget should shed trace entries")
+	}
+	for i := 0; i < 3; i++ {
+		if !b.Allow(ErrorLevel) || !b.Allow(FatalLevel) {
+			t.Fatalf("budget should always admit error entries")
+		}
+	}
+
+	want := map[Level]uint64{TraceLevel: 1, InfoLevel: 1, WarnLevel: 1}
+	if shed := b.Shed(); !reflect.DeepEqual(shed, want) {
+		t.Errorf("budget shed counts not correct: %v", shed)
+	}
+
+	// the errors are charged, so half a second refills 5 of the 6 tokens in debt
+	now = now.Add(500 * time.Millisecond)
+	if b.Allow(WarnLevel) {
+		t.Errorf("budget should not admit entries in debt")
+	}
+	now = now.Add(time.Hour)
+	for admitted = 0; b.Allow(TraceLevel); admitted++ {
+	}
+	if admitted != 5 {
+		t.Errorf("budget admitted %d trace entries after idle, want 5 keeping a reserve of 5", admitted)
+	}
+}
+
+func TestBudgetSummary(t *testing.T) {
+	now := time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	var buf bytes.Buffer
+	logger := Logger{
+		Level:  InfoLevel,
+		Writer: IOWriter{&buf},
+		Budget: &Budget{Rate: 2, SummaryInterval: time.Second},
+	}
+	derived := logger
+	derived.Context = NewContext(nil).Str("sub", "derived").Value()
+	derived.Caller = 1
+
+	for i := 0; i < 3; i++ {
+		logger.Info().Msg("hello")
+		derived.Info().Msg("hello")
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Fatalf("budget admitted %d entries, want 2: %s", n, buf.String())
+	}
+
+	buf.Reset()
+	now = now.Add(time.Second)
+	derived.Info().Msg("refilled")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("budget summary output not correct: %s", buf.String())
+	}
+	if want := `"level":"warn","shed":{"info":4},"shed_total":4,"interval":1000,"message":"log budget exceeded"}`; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("budget summary not correct: %s", lines[0])
+	}
+	if strings.Contains(lines[0], "derived") || strings.Contains(lines[0], "caller") {
+		t.Errorf("budget summary should not carry the context and caller of the logger: %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `"message":"refilled"}`) {
+		t.Errorf("budget refilled entry not correct: %s", lines[1])
+	}
+
+	buf.Reset()
+	now = now.Add(time.Second)
+	logger.Info().Msg("quiet")
+	if strings.Contains(buf.String(), "budget") {
+		t.Errorf("budget summary should not be written without shed entries: %s", buf.String())
+	}
+}
+
+func TestBudgetBytes(t *testing.T) {
+	now := time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	var buf bytes.Buffer
+	logger := Logger{
+		Writer: IOWriter{&buf},
+		Budget: &Budget{Rate: 100, Bytes: true, SummaryInterval: -1},
+	}
+
+	for i := 0; i < 3; i++ {
+		logger.Info().Msg("hello")
+	}
+	logger.Error().Msg("admitted")
+
+	// 2 entries of about 70 bytes exhaust the budget of 100 bytes
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Errorf("budget admitted %d entries, want 3: %s", n, buf.String())
+	}
+	if got := atomic.LoadInt64(&logger.Budget.tokens); got != 100-int64(buf.Len()) {
+		t.Errorf("budget tokens %d, want %d", got, 100-buf.Len())
+	}
+}
+
+func TestBudgetConcurrent(t *testing.T) {
+	now := time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	b := &Budget{Rate: 1000}
+
+	var admitted int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				if b.Allow(WarnLevel) {
+					atomic.AddInt64(&admitted, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if admitted != 1000 {
+		t.Errorf("budget admitted %d entries, want 1000", admitted)
+	}
+	if shed := b.Shed()[WarnLevel]; shed != 3000 {
+		t.Errorf("budget shed %d entries, want 3000", shed)
+	}
+}
+
+func BenchmarkBudget(b *testing.B) {
+	logger := Logger{
+		Writer: IOWriter{io.Discard},
+		Budget: &Budget{Rate: 1 << 40},
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info().Str("foo", "bar").Msg("hello world")
+	}
+}
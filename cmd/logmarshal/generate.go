@@ -0,0 +1,566 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// kind describes how the values of a type are written to an entry.
+type kind struct {
+	Method  string // the Entry method
+	Conv    string // the conversion of a named type to the parameter type of Method
+	Addr    bool   // whether the address of the value is passed to Method
+	NonZero string // the format of the omitempty condition, empty if the value is never omitted
+	Sample  string // the non-zero sample value of the generated tests, empty if none
+	Want    string // the sample value decoded from JSON, empty if it is not checked
+}
+
+var kinds = map[string]kind{
+	"string":  {Method: "Str", NonZero: `%s != ""`},
+	"bool":    {Method: "Bool", NonZero: "%s", Sample: "true", Want: "true"},
+	"int":     {Method: "Int", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"int8":    {Method: "Int8", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"int16":   {Method: "Int16", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"int32":   {Method: "Int32", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"rune":    {Method: "Int32", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"int64":   {Method: "Int64", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"uint":    {Method: "Uint", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"uint8":   {Method: "Uint8", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"byte":    {Method: "Uint8", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"uint16":  {Method: "Uint16", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"uint32":  {Method: "Uint32", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"uint64":  {Method: "Uint64", NonZero: "%s != 0", Sample: "1", Want: "float64(1)"},
+	"float32": {Method: "Float32", NonZero: "%s != 0", Sample: "1.5", Want: "1.5"},
+	"float64": {Method: "Float64", NonZero: "%s != 0", Sample: "1.5", Want: "1.5"},
+	"error":   {Method: "AnErr", NonZero: "%s != nil"},
+
+	"any":         {Method: "Any", NonZero: "%s != nil"},
+	"interface{}": {Method: "Any", NonZero: "%s != nil"},
+
+	"[]string":  {Method: "Strs", NonZero: "len(%s) != 0"},
+	"[]bool":    {Method: "Bools", NonZero: "len(%s) != 0"},
+	"[]byte":    {Method: "Bytes", NonZero: "len(%s) != 0"},
+	"[]int":     {Method: "Ints", NonZero: "len(%s) != 0"},
+	"[]int8":    {Method: "Ints8", NonZero: "len(%s) != 0"},
+	"[]int16":   {Method: "Ints16", NonZero: "len(%s) != 0"},
+	"[]int32":   {Method: "Ints32", NonZero: "len(%s) != 0"},
+	"[]int64":   {Method: "Ints64", NonZero: "len(%s) != 0"},
+	"[]uint":    {Method: "Uints", NonZero: "len(%s) != 0"},
+	"[]uint8":   {Method: "Bytes", NonZero: "len(%s) != 0"},
+	"[]uint16":  {Method: "Uints16", NonZero: "len(%s) != 0"},
+	"[]uint32":  {Method: "Uints32", NonZero: "len(%s) != 0"},
+	"[]uint64":  {Method: "Uints64", NonZero: "len(%s) != 0"},
+	"[]float32": {Method: "Floats32", NonZero: "len(%s) != 0"},
+	"[]float64": {Method: "Floats64", NonZero: "len(%s) != 0"},
+	"[]error":   {Method: "Errs", NonZero: "len(%s) != 0"},
+
+	"time.Time":          {Method: "Time", NonZero: "!%s.IsZero()"},
+	"time.Duration":      {Method: "Dur", NonZero: "%s != 0", Sample: "1"},
+	"[]time.Time":        {Method: "Times", NonZero: "len(%s) != 0"},
+	"[]time.Duration":    {Method: "Durs", NonZero: "len(%s) != 0"},
+	"net.IP":             {Method: "IPAddr", NonZero: "len(%s) != 0"},
+	"net.IPNet":          {Method: "IPPrefix"},
+	"net.HardwareAddr":   {Method: "MACAddr", NonZero: "len(%s) != 0"},
+	"net/netip.Addr":     {Method: "NetIPAddr", NonZero: "%s.IsValid()"},
+	"net/netip.AddrPort": {Method: "NetIPAddrPort", NonZero: "%s.IsValid()"},
+	"net/netip.Prefix":   {Method: "NetIPPrefix", NonZero: "%s.IsValid()"},
+}
+
+// field is a field of a struct type written by MarshalObject.
+type field struct {
+	Path      string // the selector of the field, e.g. Address.City
+	Key       string
+	Kind      kind
+	OmitEmpty bool
+	Redact    bool
+	Inline    string // the type of an embedded object which is inlined
+	Pointer   bool   // whether the inlined object is embedded by pointer
+}
+
+// generator generates the MarshalObject methods of the struct types of a package.
+type generator struct {
+	Command string   // the command line recorded in the header of the output
+	Types   []string // the names of the struct types
+
+	pkg        string
+	log        string                          // the name of the imported logstack package
+	specs      map[string]*ast.TypeSpec        // the type declarations of the package
+	files      map[string]*ast.File            // the files of the type declarations
+	imports    map[*ast.File]map[string]string // the import paths of the files by name
+	marshalers map[string]bool                 // the types with a MarshalObject method
+}
+
+// parse parses the non-test go files of dir, except the files named by exclude.
+func (g *generator) parse(dir string, exclude ...string) error {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		if strings.HasSuffix(fi.Name(), "_test.go") {
+			return false
+		}
+		for _, name := range exclude {
+			if filepath.Base(name) == fi.Name() {
+				return false
+			}
+		}
+		return true
+	}, 0)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) != 1 {
+		return fmt.Errorf("found %d packages in %s", len(pkgs), dir)
+	}
+
+	g.log = "log"
+	g.specs = make(map[string]*ast.TypeSpec)
+	g.files = make(map[string]*ast.File)
+	g.imports = make(map[*ast.File]map[string]string)
+	g.marshalers = make(map[string]bool)
+	for name, pkg := range pkgs {
+		g.pkg = name
+		for _, file := range pkg.Files {
+			g.parseFile(file)
+		}
+	}
+
+	for _, name := range g.Types {
+		spec, ok := g.specs[name]
+		switch {
+		case !ok:
+			return fmt.Errorf("type %s is not declared in %s", name, dir)
+		case spec.TypeParams != nil:
+			return fmt.Errorf("generic type %s is not supported", name)
+		}
+		if _, ok := spec.Type.(*ast.StructType); !ok {
+			return fmt.Errorf("type %s is not a struct type", name)
+		}
+		g.marshalers[name] = true
+	}
+
+	return nil
+}
+
+func (g *generator) parseFile(file *ast.File) {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil {
+			imports[spec.Name.Name] = p
+		} else {
+			imports[path.Base(p)] = p
+		}
+	}
+	g.imports[file] = imports
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				g.declare(decl.Name.Name)
+				continue
+			}
+			if decl.Name.Name != "MarshalObject" || len(decl.Recv.List) != 1 {
+				continue
+			}
+			typ := decl.Recv.List[0].Type
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ = star.X
+			}
+			if id, ok := typ.(*ast.Ident); ok {
+				g.marshalers[id.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					g.specs[spec.Name.Name] = spec
+					g.files[spec.Name.Name] = file
+					g.declare(spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						g.declare(name.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// declare records a package-level name, the logstack package is imported as logstack if the package declares log.
+func (g *generator) declare(name string) {
+	if name == "log" {
+		g.log = "logstack"
+	}
+}
+
+// typeName returns the name of a type in kinds, e.g. []time.Time, or an empty string.
+func (g *generator) typeName(file *ast.File, typ ast.Expr) string {
+	switch typ := typ.(type) {
+	case *ast.Ident:
+		return typ.Name
+	case *ast.SelectorExpr:
+		if x, ok := typ.X.(*ast.Ident); ok {
+			if p, ok := g.imports[file][x.Name]; ok {
+				return p + "." + typ.Sel.Name
+			}
+		}
+	case *ast.ArrayType:
+		if typ.Len == nil {
+			if elem := g.typeName(file, typ.Elt); elem != "" {
+				return "[]" + elem
+			}
+		}
+	case *ast.InterfaceType:
+		if typ.Methods == nil || len(typ.Methods.List) == 0 {
+			return "interface{}"
+		}
+	}
+	return ""
+}
+
+// kindOf returns the kind of a type declared in file.
+func (g *generator) kindOf(file *ast.File, typ ast.Expr, depth int) kind {
+	if k, ok := kinds[g.typeName(file, typ)]; ok {
+		return k
+	}
+
+	switch typ := typ.(type) {
+	case *ast.Ident:
+		if g.marshalers[typ.Name] {
+			return kind{Method: "Object", Addr: true}
+		}
+		spec, ok := g.specs[typ.Name]
+		if !ok || spec.TypeParams != nil || depth > 8 {
+			break
+		}
+		underlying := g.typeName(g.files[typ.Name], spec.Type)
+		if k, ok := kinds[underlying]; ok && !strings.Contains(underlying, ".") {
+			// a named type of a predeclared type, or of a slice of a predeclared type
+			k.Conv = underlying
+			return k
+		}
+		if k := g.kindOf(g.files[typ.Name], spec.Type, depth+1); k.Method == "Any" || k.Conv != "" {
+			return k
+		}
+	case *ast.StarExpr:
+		if id, ok := typ.X.(*ast.Ident); ok && g.marshalers[id.Name] {
+			return kind{Method: "Object", NonZero: "%s != nil"}
+		}
+		return kind{Method: "Any", NonZero: "%s != nil"}
+	case *ast.ArrayType:
+		if typ.Len == nil {
+			return kind{Method: "Any", NonZero: "len(%s) != 0"}
+		}
+	case *ast.MapType:
+		return kind{Method: "Any", NonZero: "len(%s) != 0"}
+	case *ast.InterfaceType, *ast.ChanType, *ast.FuncType:
+		return kind{Method: "Any", NonZero: "%s != nil"}
+	}
+
+	return kind{Method: "Any"}
+}
+
+// parseTag returns the key and the options of a field tag, the log tag takes precedence over the json tag.
+func parseTag(lit *ast.BasicLit) (key string, skip, omitempty, redact bool) {
+	if lit == nil {
+		return
+	}
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return
+	}
+	tag := reflect.StructTag(s)
+
+	if value, ok := tag.Lookup("json"); ok {
+		opts := strings.Split(value, ",")
+		if value == "-" {
+			skip = true
+		} else {
+			key = opts[0]
+		}
+		for _, opt := range opts[1:] {
+			omitempty = omitempty || opt == "omitempty"
+		}
+	}
+
+	if value, ok := tag.Lookup("log"); ok {
+		if value == "-" {
+			return "", true, false, false
+		}
+		opts := strings.Split(value, ",")
+		if opts[0] != "" {
+			key, skip = opts[0], false
+		}
+		for _, opt := range opts[1:] {
+			omitempty = omitempty || opt == "omitempty"
+			redact = redact || opt == "redact"
+		}
+	}
+
+	return
+}
+
+// fields returns the fields of a struct type written by MarshalObject, prefix is prepended to their paths.
+func (g *generator) fields(name, prefix string) []field {
+	st, ok := g.specs[name].Type.(*ast.StructType)
+	if !ok {
+		return nil
+	}
+
+	file := g.files[name]
+	var fields []field
+	for _, f := range st.Fields.List {
+		key, skip, omitempty, redact := parseTag(f.Tag)
+		if skip {
+			continue
+		}
+
+		names := f.Names
+		if len(names) == 0 {
+			// an embedded field is named by its type
+			typ, pointer := f.Type, false
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ, pointer = star.X, true
+			}
+			var id *ast.Ident
+			switch typ := typ.(type) {
+			case *ast.Ident:
+				id = typ
+			case *ast.SelectorExpr:
+				id = typ.Sel
+			default:
+				continue
+			}
+			if key == "" && typ == ast.Expr(id) && g.marshalers[id.Name] {
+				fields = append(fields, field{Path: prefix + id.Name, Inline: id.Name, Pointer: pointer})
+				continue
+			}
+			names = []*ast.Ident{id}
+		}
+
+		k := g.kindOf(file, f.Type, 0)
+		for _, id := range names {
+			if !id.IsExported() {
+				continue
+			}
+			fd := field{
+				Path:      prefix + id.Name,
+				Key:       key,
+				Kind:      k,
+				OmitEmpty: omitempty && k.NonZero != "",
+				Redact:    redact,
+			}
+			if fd.Key == "" {
+				fd.Key = id.Name
+			}
+			fields = append(fields, fd)
+		}
+	}
+
+	return fields
+}
+
+// receiver returns the receiver name of the methods of a type.
+func receiver(name string) string {
+	r := strings.ToLower(name[:1])
+	if r == "e" || r == "_" {
+		r = "o"
+	}
+	return r
+}
+
+func (g *generator) header(b *bytes.Buffer, imports ...string) {
+	fmt.Fprintf(b, "// Code generated by %q; DO NOT EDIT.\n\n", g.Command)
+	fmt.Fprintf(b, "package %s\n\n", g.pkg)
+	if len(imports) == 0 {
+		fmt.Fprintf(b, "import %s \"github.com/fabricatorsltd/logstack\"\n", g.log)
+		return
+	}
+	b.WriteString("import (\n")
+	for _, p := range imports {
+		fmt.Fprintf(b, "\t%q\n", p)
+	}
+	fmt.Fprintf(b, "\n\t%s \"github.com/fabricatorsltd/logstack\"\n)\n", g.log)
+}
+
+// source returns the source of the MarshalObject methods.
+func (g *generator) source() ([]byte, error) {
+	var b bytes.Buffer
+	g.header(&b)
+
+	for _, name := range g.Types {
+		r := receiver(name)
+		fmt.Fprintf(&b, "\n// MarshalObject implements %s.ObjectMarshaler.\n", g.log)
+		fmt.Fprintf(&b, "func (%s *%s) MarshalObject(e *%s.Entry) {\n", r, name, g.log)
+		for _, f := range g.fields(name, r+".") {
+			if f.Inline != "" {
+				if f.Pointer {
+					fmt.Fprintf(&b, "if %s != nil {\n%s.MarshalObject(e)\n}\n", f.Path, f.Path)
+				} else {
+					fmt.Fprintf(&b, "%s.MarshalObject(e)\n", f.Path)
+				}
+				continue
+			}
+
+			method, value := f.Kind.Method, f.Path
+			switch {
+			case f.Redact:
+				method, value = "Str", `"[REDACTED]"`
+			case f.Kind.Addr:
+				value = "&" + value
+			case f.Kind.Conv != "":
+				value = f.Kind.Conv + "(" + value + ")"
+			}
+			if f.OmitEmpty {
+				fmt.Fprintf(&b, "if "+f.Kind.NonZero+" {\n", f.Path)
+			}
+			fmt.Fprintf(&b, "e.%s(%s, %s)\n", method, strconv.Quote(f.Key), value)
+			if f.OmitEmpty {
+				b.WriteString("}\n")
+			}
+		}
+		b.WriteString("}\n")
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, errors.New("format generated methods: " + err.Error())
+	}
+	return src, nil
+}
+
+// testFields returns the fields of a type and the fields of its inlined objects, whose keys are known.
+func (g *generator) testFields(name, prefix string) []field {
+	var fields []field
+	for _, f := range g.fields(name, prefix) {
+		if f.Inline == "" {
+			fields = append(fields, f)
+			continue
+		}
+		// the keys of an object embedded by pointer are unknown, it is nil in the tests
+		if !f.Pointer && g.generated(f.Inline) {
+			fields = append(fields, g.testFields(f.Inline, f.Path+".")...)
+		}
+	}
+	return fields
+}
+
+func (g *generator) generated(name string) bool {
+	for _, t := range g.Types {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// testSource returns the source of the tests of the MarshalObject methods, which marshal the zero
+// value and a sample value of every type, and check the keys, the redacted and the sample values.
+func (g *generator) testSource() ([]byte, error) {
+	var b bytes.Buffer
+	g.header(&b, "encoding/json", "testing")
+
+	for _, name := range g.Types {
+		fields := g.testFields(name, "v.")
+
+		fmt.Fprintf(&b, "\nfunc Test%sMarshalObject(t *testing.T) {\n", strings.ToUpper(name[:1])+name[1:])
+		fmt.Fprintf(&b, "v := &%s{}\n", name)
+		var zero, sample struct {
+			Present, Absent []string
+			Values          []string
+		}
+		for _, f := range fields {
+			value := f.Kind.Sample
+			want := f.Kind.Want
+			if f.Kind.Method == "Str" {
+				value = strconv.Quote(f.Key)
+				want = value
+			}
+			if f.Kind.Method == "Object" || f.Kind.Method == "Any" {
+				value, want = "", ""
+			}
+			if f.Redact {
+				want = `"[REDACTED]"`
+			}
+			if value != "" {
+				fmt.Fprintf(&b, "%s = %s\n", f.Path, value)
+			}
+
+			key := strconv.Quote(f.Key)
+			if f.OmitEmpty {
+				zero.Absent = append(zero.Absent, key)
+			} else {
+				zero.Present = append(zero.Present, key)
+				if f.Redact {
+					zero.Values = append(zero.Values, key+": "+want)
+				}
+			}
+			if f.OmitEmpty && value == "" {
+				sample.Absent = append(sample.Absent, key)
+			} else {
+				sample.Present = append(sample.Present, key)
+				if want != "" {
+					sample.Values = append(sample.Values, key+": "+want)
+				}
+			}
+		}
+
+		fmt.Fprintf(&b, "\nfor _, c := range []struct {\nObject *%s\nPresent []string\nAbsent []string\nValues map[string]interface{}\n}{\n", name)
+		for _, c := range []struct {
+			Object string
+			Case   *struct{ Present, Absent, Values []string }
+		}{{"&" + name + "{}", &zero}, {"v", &sample}} {
+			fmt.Fprintf(&b, "{%s, %s, %s, %s},\n", c.Object, literal("[]string", c.Case.Present),
+				literal("[]string", c.Case.Absent), literal("map[string]interface{}", c.Case.Values))
+		}
+		b.WriteString("} {\n")
+		fmt.Fprintf(&b, `b := %s.NewContext(nil).Object("object", c.Object).Value()
+var m map[string]map[string]interface{}
+if err := json.Unmarshal(append(append([]byte{'{'}, b[1:]...), '}'), &m); err != nil {
+	t.Fatalf("%[2]s.MarshalObject returns invalid JSON %%s: %%+v", b, err)
+}
+for _, key := range c.Present {
+	if _, ok := m["object"][key]; !ok {
+		t.Errorf("%[2]s.MarshalObject %%s does not contain %%q", b, key)
+	}
+}
+for _, key := range c.Absent {
+	if _, ok := m["object"][key]; ok {
+		t.Errorf("%[2]s.MarshalObject %%s contains %%q", b, key)
+	}
+}
+for key, want := range c.Values {
+	if got := m["object"][key]; got != want {
+		t.Errorf("%[2]s.MarshalObject %%s field %%q is %%v, want %%v", b, key, got, want)
+	}
+}
+}
+}
+`, g.log, name)
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, errors.New("format generated tests: " + err.Error())
+	}
+	return src, nil
+}
+
+// literal returns the composite literal of elements, or nil if there is no element.
+func literal(typ string, elems []string) string {
+	if len(elems) == 0 {
+		return "nil"
+	}
+	return typ + "{" + strings.Join(elems, ", ") + "}"
+}
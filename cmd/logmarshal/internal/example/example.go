@@ -0,0 +1,52 @@
+// Package example declares the types whose MarshalObject methods and tests are generated by
+// logmarshal, the tests of logmarshal check that the generated files are up to date.
+package example
+
+import (
+	"net/netip"
+	"time"
+)
+
+//go:generate go run github.com/fabricatorsltd/logstack/cmd/logmarshal -type User,Base,Address,Session
+
+// Status is the status of a user account.
+type Status string
+
+// Base holds the fields common to the records.
+type Base struct {
+	ID      int64     `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+// User is a user account.
+type User struct {
+	Base
+	Name     string            `json:"name"`
+	Email    string            `json:"email,omitempty"`
+	Password string            `json:"-" log:"password,redact"`
+	Admin    bool              `json:"admin,omitempty"`
+	Status   Status            `json:"status"`
+	Age      uint8             `json:"age,omitempty"`
+	Score    float64           `json:"score"`
+	Tags     []string          `json:"tags,omitempty"`
+	Address  Address           `json:"address"`
+	Session  *Session          `json:"session,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Token    string            `json:"token" log:"-"`
+	internal int
+}
+
+// Address is a postal address.
+type Address struct {
+	Street  string `json:"street"`
+	City    string `json:"city"`
+	Country string `json:"country,omitempty"`
+}
+
+// Session is a login session of a user.
+type Session struct {
+	RemoteAddr netip.Addr    `json:"remote_addr"`
+	Started    time.Time     `json:"started"`
+	Timeout    time.Duration `json:"timeout,omitempty"`
+	Secret     []byte        `log:"secret,omitempty,redact"`
+}
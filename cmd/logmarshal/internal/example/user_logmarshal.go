@@ -0,0 +1,60 @@
+// Code generated by "logmarshal -type User,Base,Address,Session"; DO NOT EDIT.
+
+package example
+
+import log "github.com/fabricatorsltd/logstack"
+
+// MarshalObject implements log.ObjectMarshaler.
+func (u *User) MarshalObject(e *log.Entry) {
+	u.Base.MarshalObject(e)
+	e.Str("name", u.Name)
+	if u.Email != "" {
+		e.Str("email", u.Email)
+	}
+	e.Str("password", "[REDACTED]")
+	if u.Admin {
+		e.Bool("admin", u.Admin)
+	}
+	e.Str("status", string(u.Status))
+	if u.Age != 0 {
+		e.Uint8("age", u.Age)
+	}
+	e.Float64("score", u.Score)
+	if len(u.Tags) != 0 {
+		e.Strs("tags", u.Tags)
+	}
+	e.Object("address", &u.Address)
+	if u.Session != nil {
+		e.Object("session", u.Session)
+	}
+	if len(u.Labels) != 0 {
+		e.Any("labels", u.Labels)
+	}
+}
+
+// MarshalObject implements log.ObjectMarshaler.
+func (b *Base) MarshalObject(e *log.Entry) {
+	e.Int64("id", b.ID)
+	e.Time("created", b.Created)
+}
+
+// MarshalObject implements log.ObjectMarshaler.
+func (a *Address) MarshalObject(e *log.Entry) {
+	e.Str("street", a.Street)
+	e.Str("city", a.City)
+	if a.Country != "" {
+		e.Str("country", a.Country)
+	}
+}
+
+// MarshalObject implements log.ObjectMarshaler.
+func (s *Session) MarshalObject(e *log.Entry) {
+	e.NetIPAddr("remote_addr", s.RemoteAddr)
+	e.Time("started", s.Started)
+	if s.Timeout != 0 {
+		e.Dur("timeout", s.Timeout)
+	}
+	if len(s.Secret) != 0 {
+		e.Str("secret", "[REDACTED]")
+	}
+}
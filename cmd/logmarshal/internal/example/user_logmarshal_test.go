@@ -0,0 +1,163 @@
+// Code generated by "logmarshal -type User,Base,Address,Session"; DO NOT EDIT.
+
+package example
+
+import (
+	"encoding/json"
+	"testing"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+func TestUserMarshalObject(t *testing.T) {
+	v := &User{}
+	v.Base.ID = 1
+	v.Name = "name"
+	v.Email = "email"
+	v.Password = "password"
+	v.Admin = true
+	v.Status = "status"
+	v.Age = 1
+	v.Score = 1.5
+
+	for _, c := range []struct {
+		Object  *User
+		Present []string
+		Absent  []string
+		Values  map[string]interface{}
+	}{
+		{&User{}, []string{"id", "created", "name", "password", "status", "score", "address"}, []string{"email", "admin", "age", "tags", "session", "labels"}, map[string]interface{}{"password": "[REDACTED]"}},
+		{v, []string{"id", "created", "name", "email", "password", "admin", "status", "age", "score", "address"}, []string{"tags", "session", "labels"}, map[string]interface{}{"id": float64(1), "name": "name", "email": "email", "password": "[REDACTED]", "admin": true, "status": "status", "age": float64(1), "score": 1.5}},
+	} {
+		b := log.NewContext(nil).Object("object", c.Object).Value()
+		var m map[string]map[string]interface{}
+		if err := json.Unmarshal(append(append([]byte{'{'}, b[1:]...), '}'), &m); err != nil {
+			t.Fatalf("User.MarshalObject returns invalid JSON %s: %+v", b, err)
+		}
+		for _, key := range c.Present {
+			if _, ok := m["object"][key]; !ok {
+				t.Errorf("User.MarshalObject %s does not contain %q", b, key)
+			}
+		}
+		for _, key := range c.Absent {
+			if _, ok := m["object"][key]; ok {
+				t.Errorf("User.MarshalObject %s contains %q", b, key)
+			}
+		}
+		for key, want := range c.Values {
+			if got := m["object"][key]; got != want {
+				t.Errorf("User.MarshalObject %s field %q is %v, want %v", b, key, got, want)
+			}
+		}
+	}
+}
+
+func TestBaseMarshalObject(t *testing.T) {
+	v := &Base{}
+	v.ID = 1
+
+	for _, c := range []struct {
+		Object  *Base
+		Present []string
+		Absent  []string
+		Values  map[string]interface{}
+	}{
+		{&Base{}, []string{"id", "created"}, nil, nil},
+		{v, []string{"id", "created"}, nil, map[string]interface{}{"id": float64(1)}},
+	} {
+		b := log.NewContext(nil).Object("object", c.Object).Value()
+		var m map[string]map[string]interface{}
+		if err := json.Unmarshal(append(append([]byte{'{'}, b[1:]...), '}'), &m); err != nil {
+			t.Fatalf("Base.MarshalObject returns invalid JSON %s: %+v", b, err)
+		}
+		for _, key := range c.Present {
+			if _, ok := m["object"][key]; !ok {
+				t.Errorf("Base.MarshalObject %s does not contain %q", b, key)
+			}
+		}
+		for _, key := range c.Absent {
+			if _, ok := m["object"][key]; ok {
+				t.Errorf("Base.MarshalObject %s contains %q", b, key)
+			}
+		}
+		for key, want := range c.Values {
+			if got := m["object"][key]; got != want {
+				t.Errorf("Base.MarshalObject %s field %q is %v, want %v", b, key, got, want)
+			}
+		}
+	}
+}
+
+func TestAddressMarshalObject(t *testing.T) {
+	v := &Address{}
+	v.Street = "street"
+	v.City = "city"
+	v.Country = "country"
+
+	for _, c := range []struct {
+		Object  *Address
+		Present []string
+		Absent  []string
+		Values  map[string]interface{}
+	}{
+		{&Address{}, []string{"street", "city"}, []string{"country"}, nil},
+		{v, []string{"street", "city", "country"}, nil, map[string]interface{}{"street": "street", "city": "city", "country": "country"}},
+	} {
+		b := log.NewContext(nil).Object("object", c.Object).Value()
+		var m map[string]map[string]interface{}
+		if err := json.Unmarshal(append(append([]byte{'{'}, b[1:]...), '}'), &m); err != nil {
+			t.Fatalf("Address.MarshalObject returns invalid JSON %s: %+v", b, err)
+		}
+		for _, key := range c.Present {
+			if _, ok := m["object"][key]; !ok {
+				t.Errorf("Address.MarshalObject %s does not contain %q", b, key)
+			}
+		}
+		for _, key := range c.Absent {
+			if _, ok := m["object"][key]; ok {
+				t.Errorf("Address.MarshalObject %s contains %q", b, key)
+			}
+		}
+		for key, want := range c.Values {
+			if got := m["object"][key]; got != want {
+				t.Errorf("Address.MarshalObject %s field %q is %v, want %v", b, key, got, want)
+			}
+		}
+	}
+}
+
+func TestSessionMarshalObject(t *testing.T) {
+	v := &Session{}
+	v.Timeout = 1
+
+	for _, c := range []struct {
+		Object  *Session
+		Present []string
+		Absent  []string
+		Values  map[string]interface{}
+	}{
+		{&Session{}, []string{"remote_addr", "started"}, []string{"timeout", "secret"}, nil},
+		{v, []string{"remote_addr", "started", "timeout"}, []string{"secret"}, nil},
+	} {
+		b := log.NewContext(nil).Object("object", c.Object).Value()
+		var m map[string]map[string]interface{}
+		if err := json.Unmarshal(append(append([]byte{'{'}, b[1:]...), '}'), &m); err != nil {
+			t.Fatalf("Session.MarshalObject returns invalid JSON %s: %+v", b, err)
+		}
+		for _, key := range c.Present {
+			if _, ok := m["object"][key]; !ok {
+				t.Errorf("Session.MarshalObject %s does not contain %q", b, key)
+			}
+		}
+		for _, key := range c.Absent {
+			if _, ok := m["object"][key]; ok {
+				t.Errorf("Session.MarshalObject %s contains %q", b, key)
+			}
+		}
+		for key, want := range c.Values {
+			if got := m["object"][key]; got != want {
+				t.Errorf("Session.MarshalObject %s field %q is %v, want %v", b, key, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Command logmarshal generates the MarshalObject methods of struct types, which implement the
+// ObjectMarshaler of github.com/fabricatorsltd/logstack, and the tests of the methods.
+//
+// Usage:
+//
+//	logmarshal -type T[,T...] [-output file] [-tests=false] [directory]
+//
+// It is designed to run by go generate, e.g.
+//
+//	//go:generate go run github.com/fabricatorsltd/logstack/cmd/logmarshal -type User,Address
+//
+// Every exported field is written by the typed Entry method of its type, e.g. Str, Int64, Bool,
+// Time, Dur, NetIPAddr or Strs, a named type by the method of its underlying type, a type with a
+// MarshalObject method by Object, and any other type by Any. An embedded type with a MarshalObject
+// method is inlined. The key and the options of a field come from its log tag, or its json tag:
+//
+//	Email    string `json:"email,omitempty"`  // omitted if empty
+//	Password string `log:"password,redact"`   // written as "[REDACTED]"
+//	Token    string `json:"token" log:"-"`    // not written
+//
+// The methods are written to <type>_logmarshal.go in the directory of the package, where <type> is
+// the first type of -type in lower case, and the tests to <type>_logmarshal_test.go.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("logmarshal", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	typeNames := fs.String("type", "", "comma-separated list of struct type names; must be set")
+	output := fs.String("output", "", "output file name; default <directory>/<type>_logmarshal.go")
+	tests := fs.Bool("tests", true, "generate the tests of the methods to the _test.go file of the output")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: logmarshal -type T[,T...] [-output file] [-tests=false] [directory]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *typeNames == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+	if *output == "" {
+		*output = filepath.Join(dir, strings.ToLower(types[0])+"_logmarshal.go")
+	}
+	testOutput := strings.TrimSuffix(*output, ".go") + "_test.go"
+
+	err := func() error {
+		g := &generator{Command: "logmarshal " + strings.Join(args, " "), Types: types}
+		if err := g.parse(dir, *output, testOutput); err != nil {
+			return err
+		}
+		src, err := g.source()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*output, src, 0644); err != nil {
+			return err
+		}
+		if !*tests {
+			return nil
+		}
+		src, err = g.testSource()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(testOutput, src, 0644)
+	}()
+	if err != nil {
+		fmt.Fprintf(stderr, "logmarshal: %+v\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateExample(t *testing.T) {
+	g := &generator{
+		Command: "logmarshal -type User,Base,Address,Session",
+		Types:   []string{"User", "Base", "Address", "Session"},
+	}
+	if err := g.parse("internal/example", "user_logmarshal.go"); err != nil {
+		t.Fatalf("generator parse error: %+v", err)
+	}
+
+	src, err := g.source()
+	if err != nil {
+		t.Fatalf("generator source error: %+v", err)
+	}
+	testSrc, err := g.testSource()
+	if err != nil {
+		t.Fatalf("generator testSource error: %+v", err)
+	}
+
+	for name, want := range map[string][]byte{
+		"user_logmarshal.go":      src,
+		"user_logmarshal_test.go": testSrc,
+	} {
+		got, err := os.ReadFile(filepath.Join("internal/example", name))
+		if err != nil {
+			t.Fatalf("read %s error: %+v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is not up to date, run go generate ./cmd/logmarshal/...:\n%s", name, want)
+		}
+	}
+}
+
+const pkg = `package users
+
+import (
+	"net"
+	stdtime "time"
+)
+
+var log = 1
+
+type Level string
+
+type Meta struct{}
+
+func (m Meta) MarshalObject(e interface{}) {}
+
+type User struct {
+	*Meta
+	ID      int
+	name    string
+	Level   Level         ` + "`log:\",omitempty\"`" + `
+	Addr    net.IP        ` + "`json:\"addr\"`" + `
+	Timeout stdtime.Duration
+	Extra   interface{}   ` + "`json:\"extra\" log:\"-\"`" + `
+	Raw     []byte        ` + "`json:\"-\" log:\"raw\"`" + `
+}
+
+type ID int
+`
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.go"), []byte(pkg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	if code := run([]string{"-type", "User", dir}, &stderr); code != 0 {
+		t.Fatalf("logmarshal exit code %d: %s", code, stderr.String())
+	}
+
+	src, err := os.ReadFile(filepath.Join(dir, "user_logmarshal.go"))
+	if err != nil {
+		t.Fatalf("read output error: %+v", err)
+	}
+	for _, s := range []string{
+		`// Code generated by "logmarshal -type User ` + dir + `"; DO NOT EDIT.`,
+		`import logstack "github.com/fabricatorsltd/logstack"`,
+		"func (u *User) MarshalObject(e *logstack.Entry) {",
+		"if u.Meta != nil {\n\t\tu.Meta.MarshalObject(e)\n\t}",
+		`e.Int("ID", u.ID)`,
+		"if u.Level != \"\" {\n\t\te.Str(\"Level\", string(u.Level))\n\t}",
+		`e.IPAddr("addr", u.Addr)`,
+		`e.Dur("Timeout", u.Timeout)`,
+		`e.Bytes("raw", u.Raw)`,
+	} {
+		if !strings.Contains(string(src), s) {
+			t.Errorf("logmarshal output does not contain %q:\n%s", s, src)
+		}
+	}
+	for _, s := range []string{"name", "Extra"} {
+		if strings.Contains(string(src), s) {
+			t.Errorf("logmarshal output contains %q:\n%s", s, src)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "user_logmarshal_test.go")); err != nil {
+		t.Errorf("logmarshal tests not generated: %+v", err)
+	}
+
+	// the previous output is not parsed again
+	if code := run([]string{"-type", "User", "-tests=false", "-output", filepath.Join(dir, "user_logmarshal.go"), dir}, &stderr); code != 0 {
+		t.Errorf("logmarshal exit code %d: %s", code, stderr.String())
+	}
+}
+
+func TestRunError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.go"), []byte(pkg+"\ntype List[T any] struct{ Items []T }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Args   []string
+		Code   int
+		Stderr string
+	}{
+		{[]string{dir}, 2, "Usage: logmarshal"},
+		{[]string{"-type", "Group", dir}, 1, "type Group is not declared"},
+		{[]string{"-type", "ID", dir}, 1, "type ID is not a struct type"},
+		{[]string{"-type", "List", dir}, 1, "generic type List is not supported"},
+	}
+
+	for _, c := range cases {
+		var stderr bytes.Buffer
+		if code := run(c.Args, &stderr); code != c.Code || !strings.Contains(stderr.String(), c.Stderr) {
+			t.Errorf("logmarshal %v exit code %d stderr %q, want %d %q", c.Args, code, stderr.String(), c.Code, c.Stderr)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+func runCat(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("cat", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	aliases := fs.String("aliases", "auto", "field aliases preset: auto, zap, zerolog, logrus, gcp, ecs or none")
+	color := fs.Bool("color", isTerminal(stdout), "colorize the output")
+	quote := fs.Bool("quote", false, "quote string values")
+	endWithMessage := fs.Bool("end-with-message", false, "output message in the end of line")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	w := &log.ConsoleWriter{
+		ColorOutput:    *color,
+		QuoteString:    *quote,
+		EndWithMessage: *endWithMessage,
+		Writer:         stdout,
+	}
+	if *aliases != "none" {
+		if w.Aliases = log.FieldAliasesPreset(*aliases); w.Aliases == nil {
+			return fmt.Errorf("unknown field aliases preset %q", *aliases)
+		}
+	}
+
+	return eachLine(fs.Args(), stdin, func(line []byte) error {
+		_, err := w.Write(line)
+		return err
+	})
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && log.IsTerminal(f.Fd())
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCat(t *testing.T) {
+	input := `{"level":"info","ts":1573071825.123,"caller":"main.go:42","msg":"hello zap","foo":"bar"}
+{"time":"2019-07-10T05:35:54.277Z","level":"warn","message":"hello logstack"}
+not a json line
+`
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"cat", "-color=false"}, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack cat exit code %d: %s", code, stderr.String())
+	}
+
+	lines := strings.Split(stdout.String(), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("logstack cat output lines not correct: %q", stdout.String())
+	}
+	if !strings.HasSuffix(lines[0], " INF  main.go:42 > hello zap foo=bar") {
+		t.Errorf("logstack cat zap line not correct: %q", lines[0])
+	}
+	if lines[1] != "2019-07-10T05:35:54.277Z WRN > hello logstack" {
+		t.Errorf("logstack cat logstack line not correct: %q", lines[1])
+	}
+	if lines[2] != "not a json line" {
+		t.Errorf("logstack cat raw line not correct: %q", lines[2])
+	}
+}
+
+func TestCatGzip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "main.log.gz")
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(`{"time":"2019-07-10T05:35:54.277Z","level":"error","message":"hello gzip"}` + "\n"))
+	gz.Close()
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write gzip file error: %+v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"cat", "-color=false", filename}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack cat exit code %d: %s", code, stderr.String())
+	}
+	if got, want := stdout.String(), "2019-07-10T05:35:54.277Z ERR > hello gzip\n"; got != want {
+		t.Errorf("logstack cat gzip want=%q got=%q", want, got)
+	}
+}
+
+func TestCatGzipActive(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "main.log.gz")
+
+	// an unterminated stream flushed after the first entry
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(`{"time":"2019-07-10T05:35:54.277Z","level":"info","message":"flushed"}` + "\n"))
+	_ = gz.Flush()
+	_, _ = gz.Write([]byte(`{"time":"2019-07-10T05:35:55.277Z","level":"info","message":"pending"}` + "\n"))
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write gzip file error: %+v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"cat", "-color=false", filename}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack cat exit code %d: %s", code, stderr.String())
+	}
+	if got, want := stdout.String(), "2019-07-10T05:35:54.277Z INF > flushed\n"; got != want {
+		t.Errorf("logstack cat active gzip want=%q got=%q", want, got)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"nope"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("logstack unknown command exit code %d", code)
+	}
+	if code := run([]string{"cat", "-aliases", "nope"}, strings.NewReader("{}\n"), &stdout, &stderr); code != 1 {
+		t.Errorf("logstack cat unknown aliases exit code %d", code)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+func runJournal(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("journal", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	console := fs.Bool("console", false, "render the entries in a human-friendly format instead of JSON")
+	color := fs.Bool("color", isTerminal(stdout), "colorize the output of -console")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var w log.Writer = log.IOWriter{Writer: stdout}
+	if *console {
+		w = &log.ConsoleWriter{ColorOutput: *color, Writer: stdout}
+	}
+
+	replay := func(r io.Reader) error {
+		jr := log.NewJournalReader(r)
+		for {
+			e, err := jr.ReadEntry()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := w.WriteEntry(e); err != nil {
+				return err
+			}
+		}
+	}
+
+	if fs.NArg() == 0 {
+		return replay(stdin)
+	}
+	for _, name := range fs.Args() {
+		r, err := openInput(name)
+		if err != nil {
+			return err
+		}
+		err = replay(r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"journal", "../../testdata/journal.export", "../../testdata/journal.json"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack journal exit code %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("logstack journal output lines not correct: %s", stdout.String())
+	}
+	if lines[1] != `{"time":"2019-07-10T05:35:54.999Z","level":"info","caller":"main.go:42","foo":"bar","message":"hello journal writer"}` {
+		t.Errorf("logstack journal writer line not correct: %s", lines[1])
+	}
+
+	data, err := os.ReadFile("../../testdata/journal.export")
+	if err != nil {
+		t.Fatalf("read journal fixture error: %+v", err)
+	}
+	stdout.Reset()
+	if code := run([]string{"journal", "-console", "-color=false"}, bytes.NewReader(data), &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack journal -console exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "2019-07-10T05:35:56.500Z ??? > eth0: link up _transport=kernel\n") {
+		t.Errorf("logstack journal -console output not correct: %s", stdout.String())
+	}
+}
@@ -0,0 +1,132 @@
+// Command logstack is a toolbox for the JSON logs written by github.com/fabricatorsltd/logstack.
+//
+// Usage:
+//
+//	logstack <command> [flags] [file ...]
+//
+// Files ending with .gz are decompressed transparently, including the active gzip stream of a
+// FileWriter up to its last flush, stdin is read if no file is given.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+type command struct {
+	Usage string
+	Run   func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"cat":       {"render JSON logs in a human-friendly format", runCat},
+	"journal":   {"convert journalctl -o export or -o json output to JSON logs", runJournal},
+	"parquet":   {"convert JSON logs to Parquet files", runParquet},
+	"report":    {"generate a self-contained HTML report of a time window", runReport},
+	"scrub":     {"remove or redact matching entries of log files and backups", runScrub},
+	"symbolize": {"replace the stack_pcs fields with readable stack traces", runSymbolize},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "logstack: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	if err := cmd.Run(args[1:], stdin, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "logstack %s: %+v\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: logstack <command> [flags] [file ...]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].Usage)
+	}
+}
+
+// openInput opens a log file for reading, decompressing it if the name ends with .gz.
+func openInput(name string) (io.ReadCloser, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return file, nil
+	}
+	gz, err := log.NewGzipReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, file}, nil
+}
+
+// eachLine calls fn with every line of the files, or of stdin if files is empty.
+func eachLine(files []string, stdin io.Reader, fn func(line []byte) error) error {
+	read := func(r io.Reader) error {
+		br := bufio.NewReaderSize(r, 64*1024)
+		for {
+			line, err := br.ReadBytes('\n')
+			if len(line) > 0 {
+				if err1 := fn(line); err1 != nil {
+					return err1
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return read(stdin)
+	}
+
+	for _, name := range files {
+		r, err := openInput(name)
+		if err != nil {
+			return err
+		}
+		err = read(r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
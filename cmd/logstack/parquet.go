@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fabricatorsltd/logstack/parquet"
+)
+
+func runParquet(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("parquet", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "write all files to one output file instead of a .parquet file next to each input")
+	compression := fs.String("compression", "gzip", "page compression: gzip or none")
+	rowGroupSize := fs.Int("row-group-size", 0, "number of rows of a row group, the default is 65536")
+	sampleSize := fs.Int("sample-size", 0, "number of leading entries to infer the schema from, the default is 1000")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	newWriter := func(w io.Writer) *parquet.Writer {
+		pw := &parquet.Writer{
+			Writer:       w,
+			RowGroupSize: *rowGroupSize,
+			SampleSize:   *sampleSize,
+		}
+		if *compression == "gzip" {
+			pw.Compression = parquet.Gzip
+		}
+		return pw
+	}
+	switch *compression {
+	case "gzip", "none":
+	default:
+		return fmt.Errorf("unknown compression %q", *compression)
+	}
+
+	convert := func(files []string, name string) error {
+		if name == "" {
+			return writeParquet(newWriter(stdout), files, stdin)
+		}
+		file, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		if err = writeParquet(newWriter(file), files, stdin); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+
+	files := fs.Args()
+	if len(files) == 0 || *output != "" {
+		return convert(files, *output)
+	}
+	for _, name := range files {
+		if err := convert([]string{name}, parquetName(name)); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "%s -> %s\n", name, parquetName(name))
+	}
+	return nil
+}
+
+func writeParquet(pw *parquet.Writer, files []string, stdin io.Reader) error {
+	err := eachLine(files, stdin, func(line []byte) error {
+		_, err := pw.Write(line)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// parquetName returns the output filename of a log file, app.2019-07-10T05-35-54.log.gz becomes app.2019-07-10T05-35-54.parquet.
+func parquetName(name string) string {
+	name = strings.TrimSuffix(name, ".gz")
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".parquet"
+}
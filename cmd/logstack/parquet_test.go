@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fabricatorsltd/logstack/parquet"
+)
+
+func TestParquet(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "main.2019-07-10T05-35-54.log")
+	if err := os.WriteFile(plain, []byte(`{"time":"2019-07-10T05:35:54.277Z","level":"info","message":"hello"}`+"\n"), 0644); err != nil {
+		t.Fatalf("write log file error: %+v", err)
+	}
+	compressed := filepath.Join(dir, "main.2019-07-11T05-35-54.log.gz")
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(`{"time":"2019-07-11T05:35:54.277Z","level":"warn","message":"hello gzip"}` + "\n"))
+	gz.Close()
+	if err := os.WriteFile(compressed, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("write gzip file error: %+v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"parquet", plain, compressed}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack parquet exit code %d: %s", code, stderr.String())
+	}
+	for name, message := range map[string]string{
+		"main.2019-07-10T05-35-54.parquet": "hello",
+		"main.2019-07-11T05-35-54.parquet": "hello gzip",
+	} {
+		rows := readParquet(t, filepath.Join(dir, name))
+		if len(rows) != 1 || rows[0]["message"] != message {
+			t.Errorf("logstack parquet %s rows not correct: %+v", name, rows)
+		}
+	}
+
+	output := filepath.Join(dir, "all.parquet")
+	if code := run([]string{"parquet", "-o", output, "-compression", "none", plain, compressed}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack parquet exit code %d: %s", code, stderr.String())
+	}
+	if rows := readParquet(t, output); len(rows) != 2 || rows[1]["level"] != "warn" {
+		t.Errorf("logstack parquet combined rows not correct: %+v", rows)
+	}
+}
+
+func TestParquetStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	input := `{"level":"error","message":"hello stdin"}` + "\n"
+	if code := run([]string{"parquet"}, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack parquet exit code %d: %s", code, stderr.String())
+	}
+	r, err := parquet.NewReader(bytes.NewReader(stdout.Bytes()), int64(stdout.Len()))
+	if err != nil {
+		t.Fatalf("parquet NewReader error: %+v", err)
+	}
+	if rows, err := r.ReadRows(); err != nil || len(rows) != 1 || rows[0]["message"] != "hello stdin" {
+		t.Errorf("logstack parquet stdin rows not correct: %+v %+v", rows, err)
+	}
+
+	if code := run([]string{"parquet", "-compression", "zstd"}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("logstack parquet unknown compression exit code %d", code)
+	}
+}
+
+func readParquet(t *testing.T, name string) []map[string]interface{} {
+	t.Helper()
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("open parquet file error: %+v", err)
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		t.Fatalf("stat parquet file error: %+v", err)
+	}
+	r, err := parquet.NewReader(file, fi.Size())
+	if err != nil {
+		t.Fatalf("parquet NewReader error: %+v", err)
+	}
+	rows, err := r.ReadRows()
+	if err != nil {
+		t.Fatalf("parquet ReadRows error: %+v", err)
+	}
+	return rows
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/fabricatorsltd/logstack"
+	"github.com/fabricatorsltd/logstack/report"
+)
+
+func runReport(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "write the report to a file instead of stdout")
+	title := fs.String("title", "", "title of the report")
+	since := fs.String("since", "", "inclusive start of the window in RFC3339, e.g. 2019-07-10T05:00:00Z")
+	until := fs.String("until", "", "exclusive end of the window in RFC3339")
+	buckets := fs.Int("buckets", 0, "number of bars of the chart, the default is 60")
+	top := fs.Int("top", 0, "number of top error messages and callers, the default is 10")
+	maxEntries := fs.Int("max-entries", 0, "maximum number of listed entries, the default is 10000")
+	aliases := fs.String("aliases", "auto", "field aliases preset: auto, zap, zerolog, logrus, gcp, ecs or none")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r := &report.Report{
+		Title:      *title,
+		Buckets:    *buckets,
+		TopN:       *top,
+		MaxEntries: *maxEntries,
+	}
+	var err error
+	if *since != "" {
+		if r.Start, err = time.Parse(time.RFC3339Nano, *since); err != nil {
+			return fmt.Errorf("invalid -since: %w", err)
+		}
+	}
+	if *until != "" {
+		if r.End, err = time.Parse(time.RFC3339Nano, *until); err != nil {
+			return fmt.Errorf("invalid -until: %w", err)
+		}
+	}
+	if *aliases != "none" {
+		if r.Aliases = log.FieldAliasesPreset(*aliases); r.Aliases == nil {
+			return fmt.Errorf("unknown field aliases preset %q", *aliases)
+		}
+	}
+
+	if fs.NArg() == 0 {
+		if err = r.Add("stdin", stdin); err != nil {
+			return err
+		}
+	}
+	for _, name := range fs.Args() {
+		f, err := openInput(name)
+		if err != nil {
+			return err
+		}
+		err = r.Add(name, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if *output == "" {
+		return r.WriteHTML(stdout)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err = r.WriteHTML(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "main.log")
+	data := `{"time":"2019-07-10T05:35:54.277Z","level":"info","message":"hello"}` + "\n" +
+		`{"time":"2019-07-10T05:36:54.277Z","level":"error","caller":"main.go:42","message":"boom"}` + "\n"
+	if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatalf("write log file error: %+v", err)
+	}
+
+	output := filepath.Join(dir, "report.html")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"report", "-o", output, "-title", "outage", "-since", "2019-07-10T05:36:00Z", name}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack report exit code %d: %s", code, stderr.String())
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read report error: %+v", err)
+	}
+	html := string(b)
+	if !strings.Contains(html, "<title>outage</title>") || !strings.Contains(html, "<summary>boom</summary>") || strings.Contains(html, "<summary>hello</summary>") {
+		t.Errorf("logstack report output not correct: %s", html)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"report"}, strings.NewReader(data), &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack report exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `<span class="mono">stdin</span> (2)`) {
+		t.Errorf("logstack report stdin output not correct: %s", stdout.String())
+	}
+
+	if code := run([]string{"report", "-since", "yesterday"}, strings.NewReader(data), &stdout, &stderr); code != 1 {
+		t.Errorf("logstack report invalid -since exit code %d", code)
+	}
+}
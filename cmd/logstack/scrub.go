@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+// matchFlags is a repeatable flag of key=value pairs.
+type matchFlags map[string][]string
+
+func (m matchFlags) String() string {
+	return ""
+}
+
+func (m matchFlags) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return fmt.Errorf("invalid match %q, want key=value", s)
+	}
+	m[s[:i]] = append(m[s[:i]], s[i+1:])
+	return nil
+}
+
+func runScrub(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("scrub", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	matches := matchFlags{}
+	fs.Var(matches, "match", "scrub the entries with a top-level field key=value, repeatable")
+	remove := fs.Bool("remove", false, "remove the matching entries instead of redacting the matching fields")
+	redaction := fs.String("redaction", "[REDACTED]", "replacement of the redacted fields")
+	audit := fs.String("audit", "", "append the audit summary to a file instead of stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: logstack scrub -match key=value [flags] filename ...")
+		fmt.Fprintln(stderr, "\nScrubs the log file and all rotated backups of each FileWriter filename.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(matches) == 0 || fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing -match or filename")
+	}
+
+	s := &log.Scrubber{
+		Match: func(key, value string) bool {
+			for _, v := range matches[key] {
+				if v == value {
+					return true
+				}
+			}
+			return false
+		},
+		Remove:    *remove,
+		Redaction: *redaction,
+		Audit:     &log.Logger{Level: log.InfoLevel, Writer: log.IOWriter{Writer: stderr}},
+	}
+	if *audit != "" {
+		file, err := os.OpenFile(*audit, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		s.Audit.Writer = log.IOWriter{Writer: file}
+	}
+
+	for _, filename := range fs.Args() {
+		if _, err := s.ScrubFiles(filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
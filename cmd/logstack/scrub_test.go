@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScrub(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	backup := filepath.Join(dir, "app.2019-07-10T05-35-54.log")
+	input := `{"level":"info","user_id":"u-7781","email":"a@b.c","message":"a"}` + "\n" + `{"level":"info","user_id":"u-1","message":"b"}` + "\n"
+	for _, name := range []string{filename, backup} {
+		if err := os.WriteFile(name, []byte(input), 0644); err != nil {
+			t.Fatalf("write log file error: %+v", err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"scrub", "-match", "user_id=u-7781", "-match", "email=a@b.c", filename}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack scrub exit code %d: %s", code, stderr.String())
+	}
+	want := `{"level":"info","user_id":"[REDACTED]","email":"[REDACTED]","message":"a"}` + "\n" + `{"level":"info","user_id":"u-1","message":"b"}` + "\n"
+	for _, name := range []string{filename, backup} {
+		if b, _ := os.ReadFile(name); string(b) != want {
+			t.Errorf("logstack scrub %s not correct: %s", name, b)
+		}
+	}
+	if !strings.Contains(stderr.String(), `"files":2,"entries":4,"removed":0,"redacted":4`) {
+		t.Errorf("logstack scrub audit not correct: %s", stderr.String())
+	}
+
+	audit := filepath.Join(t.TempDir(), "audit.log")
+	stderr.Reset()
+	if code := run([]string{"scrub", "-match", "user_id=u-1", "-remove", "-audit", audit, filename}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack scrub exit code %d: %s", code, stderr.String())
+	}
+	if b, _ := os.ReadFile(backup); strings.Contains(string(b), "u-1") {
+		t.Errorf("logstack scrub -remove not correct: %s", b)
+	}
+	if b, _ := os.ReadFile(audit); !strings.Contains(string(b), `"removed":2`) || stderr.Len() != 0 {
+		t.Errorf("logstack scrub -audit not correct: %s %s", b, stderr.String())
+	}
+
+	if code := run([]string{"scrub", filename}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("logstack scrub without -match exit code %d", code)
+	}
+}
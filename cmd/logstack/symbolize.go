@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+func runSymbolize(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("symbolize", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	binary := fs.String("binary", "", "the binary which logged the stack_pcs fields")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *binary == "" {
+		return errors.New("missing -binary")
+	}
+
+	s, err := log.NewSymbolizer(*binary)
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(eachLine(fs.Args(), stdin, func(line []byte) error {
+			_, err := pw.Write(line)
+			return err
+		}))
+	}()
+	n, err := s.SymbolizeEntries(stdout, pr)
+	pr.Close()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Fprintf(stderr, "no stack_pcs of build id %s found\n", s.BuildID())
+	}
+	return nil
+}
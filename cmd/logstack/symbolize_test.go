@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+func TestSymbolize(t *testing.T) {
+	binary, err := os.Executable()
+	if err != nil {
+		t.Skipf("os.Executable error: %+v", err)
+	}
+
+	var input bytes.Buffer
+	logger := log.Logger{Writer: log.IOWriter{Writer: &input}}
+	logger.Error().StackPCs().Msg("compact stack")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"symbolize", "-binary", binary}, &input, &stdout, &stderr); code != 0 {
+		t.Fatalf("logstack symbolize exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"stack":"github.com/fabricatorsltd/logstack/cmd/logstack.TestSymbolize(...)\n\t`) {
+		t.Errorf("logstack symbolize output not correct: %s", stdout.String())
+	}
+
+	stderr.Reset()
+	if code := run([]string{"symbolize", "-binary", binary}, strings.NewReader(`{"message":"no stack"}`+"\n"), &stdout, &stderr); code != 0 || !strings.Contains(stderr.String(), "no stack_pcs") {
+		t.Errorf("logstack symbolize without stack_pcs not correct: %d %s", code, stderr.String())
+	}
+
+	if code := run([]string{"symbolize"}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("logstack symbolize without -binary exit code %d", code)
+	}
+}
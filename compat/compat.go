@@ -0,0 +1,28 @@
+// Package compat provides dependency-free facades of go-kit, zap and zerolog call sites over log.Logger,
+// so that services migrate to log without rewriting their call sites.
+//
+// The facades report the caller of their methods, as log.Logger does for its own call sites.
+package compat
+
+import (
+	log "github.com/fabricatorsltd/logstack"
+)
+
+// skip returns a copy of the logger whose caller is depth frames further from its methods.
+func skip(l *log.Logger, depth int) *log.Logger {
+	logger := *l
+	switch {
+	case logger.Caller > 0:
+		logger.Caller += depth
+	case logger.Caller < 0:
+		logger.Caller -= depth
+	}
+	return &logger
+}
+
+// with returns a copy of the logger with the contextual fields of ctx appended.
+func with(l *log.Logger, ctx log.Context) *log.Logger {
+	logger := *l
+	logger.Context = append(append(log.Context(nil), l.Context...), ctx...)
+	return &logger
+}
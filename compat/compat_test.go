@@ -0,0 +1,185 @@
+package compat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+type kitLevel string
+
+func (l kitLevel) String() string { return string(l) }
+
+func newTestLogger(buf *bytes.Buffer) *log.Logger {
+	return &log.Logger{
+		Level:  log.InfoLevel,
+		Caller: 1,
+		Writer: log.IOWriter{Writer: buf},
+	}
+}
+
+// line returns the file:line of its caller plus offset.
+func line(offset int) string {
+	_, file, n, _ := runtime.Caller(1)
+	return fmt.Sprintf("%s:%d", file[len(file)-len("compat_test.go"):], n+offset)
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("decode entry error: %+v", err)
+		}
+		delete(m, "time")
+		delete(m, "goid")
+		entries = append(entries, m)
+	}
+	buf.Reset()
+	return entries
+}
+
+func TestKitLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewKitLogger(newTestLogger(&buf)).With("component", "billing")
+
+	want := line(1)
+	_ = logger.Log("level", kitLevel("warn"), "msg", "charge failed", "amount", 42, "err", errors.New("declined"))
+	_ = logger.Log("level", "debug", "msg", "filtered")
+	want2 := line(1)
+	_ = logger.Log("msg", "no level", "dangling")
+
+	entries := decode(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("kit logger entries not correct: %v", entries)
+	}
+	if got := fmt.Sprint(entries[0]); got != fmt.Sprint(map[string]interface{}{"level": "warn", "caller": want, "component": "billing", "amount": 42.0, "err": "declined", "message": "charge failed"}) {
+		t.Errorf("kit logger entry not correct: %s", got)
+	}
+	if got := fmt.Sprint(entries[1]); got != fmt.Sprint(map[string]interface{}{"level": "info", "caller": want2, "component": "billing", "dangling": "(MISSING)", "message": "no level"}) {
+		t.Errorf("kit logger entry without level not correct: %s", got)
+	}
+}
+
+func TestZapLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewZapLogger(newTestLogger(&buf)).Named("api").With(String("component", "billing")).Named("v1")
+
+	want := line(1)
+	logger.Info("request served",
+		Int("status", 200),
+		Uint8("retries", 3),
+		Float64("ratio", 0.5),
+		Bool("cached", true),
+		Duration("elapsed", 1500*time.Millisecond),
+		Time("at", time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC)),
+		Strings("tags", []string{"a", "b"}),
+		Error(nil),
+		NamedError("cause", errors.New("timeout")),
+		Stringer("stringer", nil),
+		Any("any", int16(7)),
+		Skip(),
+	)
+	logger.Debug("filtered", String("foo", "bar"))
+
+	entries := decode(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("zap logger entries not correct: %v", entries)
+	}
+	wantEntry := map[string]interface{}{
+		"level":     "info",
+		"caller":    want,
+		"component": "billing",
+		"logger":    "api.v1",
+		"status":    200.0,
+		"retries":   3.0,
+		"ratio":     0.5,
+		"cached":    true,
+		"elapsed":   1500.0,
+		"at":        "2019-07-10T05:35:54Z",
+		"tags":      []interface{}{"a", "b"},
+		"cause":     "timeout",
+		"stringer":  nil,
+		"any":       7.0,
+		"message":   "request served",
+	}
+	if got := fmt.Sprint(entries[0]); got != fmt.Sprint(wantEntry) {
+		t.Errorf("zap logger entry not correct:\n%s\n%s", got, fmt.Sprint(wantEntry))
+	}
+	if err := logger.Sync(); err != nil {
+		t.Errorf("zap logger sync error: %+v", err)
+	}
+}
+
+func TestZerologLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewZerologLogger(newTestLogger(&buf)).With().Str("component", "billing").Timestamp().Logger()
+
+	want := line(1)
+	logger.Warn().Str("path", "/charge").Int("status", 402).Dict("user", Dict().Str("id", "u-1")).Fields([]interface{}{"n", 1}).Msg("charge failed")
+	logger.Debug().Str("foo", "bar").Msg("filtered")
+	// WithLevel is not eliminated by the logstack_nodebug build tag, unlike Debug
+	want2 := line(1)
+	logger.Level(log.DebugLevel).WithLevel(log.DebugLevel).Send()
+	want3 := line(1)
+	logger.Info().Caller(0).Msgf("hello %s", "world")
+
+	entries := decode(t, &buf)
+	if len(entries) != 3 {
+		t.Fatalf("zerolog logger entries not correct: %v", entries)
+	}
+	if got := fmt.Sprint(entries[0]); got != fmt.Sprint(map[string]interface{}{"level": "warn", "caller": want, "component": "billing", "path": "/charge", "status": 402.0, "user": map[string]interface{}{"id": "u-1"}, "n": 1.0, "message": "charge failed"}) {
+		t.Errorf("zerolog logger entry not correct: %s", got)
+	}
+	if got := fmt.Sprint(entries[1]); got != fmt.Sprint(map[string]interface{}{"level": "debug", "caller": want2, "component": "billing"}) {
+		t.Errorf("zerolog logger send not correct: %s", got)
+	}
+	if entries[2]["caller"] != want3 || entries[2]["message"] != "hello world" {
+		t.Errorf("zerolog logger event caller not correct: %v", entries[2])
+	}
+}
+
+func BenchmarkLogger(b *testing.B) {
+	logger := &log.Logger{Level: log.InfoLevel, Writer: log.IOWriter{Writer: io.Discard}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info().Str("path", "/charge").Int("status", 200).Msg("request served")
+	}
+}
+
+func BenchmarkKitLogger(b *testing.B) {
+	logger := NewKitLogger(&log.Logger{Level: log.InfoLevel, Writer: log.IOWriter{Writer: io.Discard}})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = logger.Log("level", "info", "msg", "request served", "path", "/charge", "status", 200)
+	}
+}
+
+func BenchmarkZapLogger(b *testing.B) {
+	logger := NewZapLogger(&log.Logger{Level: log.InfoLevel, Writer: log.IOWriter{Writer: io.Discard}})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("request served", String("path", "/charge"), Int("status", 200))
+	}
+}
+
+func BenchmarkZerologLogger(b *testing.B) {
+	logger := NewZerologLogger(&log.Logger{Level: log.InfoLevel, Writer: log.IOWriter{Writer: io.Discard}})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info().Str("path", "/charge").Int("status", 200).Msg("request served")
+	}
+}
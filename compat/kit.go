@@ -0,0 +1,78 @@
+package compat
+
+import (
+	"fmt"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+// KitLogger is a go-kit compatible logger, it implements the Logger interface of github.com/go-kit/log
+// without importing it.
+//
+// The "level" key sets the level of the entry from a level name or a fmt.Stringer, such as the values of
+// github.com/go-kit/log/level, and entries without it are logged at info level. The "msg" key sets the
+// message, and the other keys are logged as fields.
+type KitLogger struct {
+	logger  *log.Logger
+	keyvals []interface{}
+}
+
+// NewKitLogger returns a KitLogger writing to the logger.
+func NewKitLogger(l *log.Logger) *KitLogger {
+	return &KitLogger{logger: skip(l, 1)}
+}
+
+// Log implements the Logger interface of github.com/go-kit/log.
+func (k *KitLogger) Log(keyvals ...interface{}) error {
+	level, msg := log.InfoLevel, ""
+	for _, kvs := range [2][]interface{}{k.keyvals, keyvals} {
+		for i := 0; i+1 < len(kvs); i += 2 {
+			switch kvs[i] {
+			case "level":
+				level = log.ParseLevel(kitString(kvs[i+1]))
+			case "msg":
+				msg = kitString(kvs[i+1])
+			}
+		}
+	}
+
+	e := k.logger.WithLevel(level)
+	if e == nil {
+		return nil
+	}
+	for _, kvs := range [2][]interface{}{k.keyvals, keyvals} {
+		for i := 0; i < len(kvs); i += 2 {
+			key := kitString(kvs[i])
+			switch {
+			case i+1 == len(kvs):
+				e.Str(key, "(MISSING)")
+			case key != "level" && key != "msg":
+				e.Any(key, kvs[i+1])
+			}
+		}
+	}
+	e.Msg(msg)
+	return nil
+}
+
+// With returns a KitLogger which prepends keyvals to the keyvals of each entry, in the manner of
+// the With function of github.com/go-kit/log.
+func (k *KitLogger) With(keyvals ...interface{}) *KitLogger {
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals[:len(keyvals):len(keyvals)], "(MISSING)")
+	}
+	return &KitLogger{
+		logger:  k.logger,
+		keyvals: append(k.keyvals[:len(k.keyvals):len(k.keyvals)], keyvals...),
+	}
+}
+
+func kitString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
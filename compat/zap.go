@@ -0,0 +1,283 @@
+package compat
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+// ZapLogger is a zap-style logger of typed fields, the methods take a message and the fields
+// built by the field constructors of the package, e.g.
+//
+//	logger.Info("request served", compat.String("path", path), compat.Duration("elapsed", elapsed))
+type ZapLogger struct {
+	logger *log.Logger
+	name   string
+}
+
+// NewZapLogger returns a ZapLogger writing to the logger.
+func NewZapLogger(l *log.Logger) *ZapLogger {
+	return &ZapLogger{logger: skip(l, 2)}
+}
+
+// Debug logs a message at debug level.
+func (z *ZapLogger) Debug(msg string, fields ...Field) {
+	z.log(log.DebugLevel, msg, fields)
+}
+
+// Info logs a message at info level.
+func (z *ZapLogger) Info(msg string, fields ...Field) {
+	z.log(log.InfoLevel, msg, fields)
+}
+
+// Warn logs a message at warn level.
+func (z *ZapLogger) Warn(msg string, fields ...Field) {
+	z.log(log.WarnLevel, msg, fields)
+}
+
+// Error logs a message at error level.
+func (z *ZapLogger) Error(msg string, fields ...Field) {
+	z.log(log.ErrorLevel, msg, fields)
+}
+
+// Panic logs a message at panic level, then panics.
+func (z *ZapLogger) Panic(msg string, fields ...Field) {
+	z.log(log.PanicLevel, msg, fields)
+}
+
+// Fatal logs a message at fatal level, then calls os.Exit(255).
+func (z *ZapLogger) Fatal(msg string, fields ...Field) {
+	z.log(log.FatalLevel, msg, fields)
+}
+
+func (z *ZapLogger) log(level log.Level, msg string, fields []Field) {
+	e := z.logger.WithLevel(level)
+	if e == nil {
+		return
+	}
+	if z.name != "" {
+		e.Str("logger", z.name)
+	}
+	for i := range fields {
+		fields[i].apply(e)
+	}
+	e.Msg(msg)
+}
+
+// With returns a ZapLogger with the contextual fields.
+func (z *ZapLogger) With(fields ...Field) *ZapLogger {
+	e := log.NewContext(nil)
+	for i := range fields {
+		fields[i].apply(e)
+	}
+	return &ZapLogger{logger: with(z.logger, e.Value()), name: z.name}
+}
+
+// Named returns a ZapLogger with the "logger" field of the name, joined to the name of z with a period.
+func (z *ZapLogger) Named(name string) *ZapLogger {
+	if z.name != "" {
+		name = z.name + "." + name
+	}
+	return &ZapLogger{logger: z.logger, name: name}
+}
+
+// Sync flushes the writer of the logger if it has a Sync method.
+func (z *ZapLogger) Sync() error {
+	if s, ok := z.logger.Writer.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+type fieldType uint8
+
+const (
+	skipType fieldType = iota
+	anyType
+	boolType
+	byteStringType
+	durationType
+	errorType
+	float32Type
+	float64Type
+	int64Type
+	objectType
+	stringType
+	stringerType
+	stringsType
+	timeType
+	uint64Type
+)
+
+// Field is a typed field of ZapLogger, built by the field constructors of the package.
+type Field struct {
+	Key     string
+	typ     fieldType
+	integer int64
+	str     string
+	iface   interface{}
+}
+
+func (f *Field) apply(e *log.Entry) {
+	switch f.typ {
+	case anyType:
+		e.Any(f.Key, f.iface)
+	case boolType:
+		e.Bool(f.Key, f.integer == 1)
+	case byteStringType:
+		e.Bytes(f.Key, f.iface.([]byte))
+	case durationType:
+		e.Dur(f.Key, time.Duration(f.integer))
+	case errorType:
+		e.AnErr(f.Key, f.iface.(error))
+	case float32Type:
+		e.Float32(f.Key, math.Float32frombits(uint32(f.integer)))
+	case float64Type:
+		e.Float64(f.Key, math.Float64frombits(uint64(f.integer)))
+	case int64Type:
+		e.Int64(f.Key, f.integer)
+	case objectType:
+		obj, _ := f.iface.(log.ObjectMarshaler)
+		e.Object(f.Key, obj)
+	case stringType:
+		e.Str(f.Key, f.str)
+	case stringerType:
+		val, _ := f.iface.(fmt.Stringer)
+		e.Stringer(f.Key, val)
+	case stringsType:
+		e.Strs(f.Key, f.iface.([]string))
+	case timeType:
+		e.Time(f.Key, f.iface.(time.Time))
+	case uint64Type:
+		e.Uint64(f.Key, uint64(f.integer))
+	}
+}
+
+// Skip is a no-op field.
+func Skip() Field {
+	return Field{typ: skipType}
+}
+
+// Any is a field of any value, logged in the manner of log.Entry.Any.
+func Any(key string, val interface{}) Field {
+	return Field{Key: key, typ: anyType, iface: val}
+}
+
+// Bool is a bool field.
+func Bool(key string, val bool) Field {
+	var i int64
+	if val {
+		i = 1
+	}
+	return Field{Key: key, typ: boolType, integer: i}
+}
+
+// ByteString is a field of UTF-8 encoded bytes.
+func ByteString(key string, val []byte) Field {
+	return Field{Key: key, typ: byteStringType, iface: val}
+}
+
+// Duration is a time.Duration field.
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, typ: durationType, integer: int64(val)}
+}
+
+// Error is an error field of the "error" key, a nil err is skipped.
+func Error(err error) Field {
+	return NamedError("error", err)
+}
+
+// NamedError is an error field, a nil err is skipped.
+func NamedError(key string, err error) Field {
+	if err == nil {
+		return Skip()
+	}
+	return Field{Key: key, typ: errorType, iface: err}
+}
+
+// Float32 is a float32 field.
+func Float32(key string, val float32) Field {
+	return Field{Key: key, typ: float32Type, integer: int64(math.Float32bits(val))}
+}
+
+// Float64 is a float64 field.
+func Float64(key string, val float64) Field {
+	return Field{Key: key, typ: float64Type, integer: int64(math.Float64bits(val))}
+}
+
+// Int is an int field.
+func Int(key string, val int) Field {
+	return Int64(key, int64(val))
+}
+
+// Int8 is an int8 field.
+func Int8(key string, val int8) Field {
+	return Int64(key, int64(val))
+}
+
+// Int16 is an int16 field.
+func Int16(key string, val int16) Field {
+	return Int64(key, int64(val))
+}
+
+// Int32 is an int32 field.
+func Int32(key string, val int32) Field {
+	return Int64(key, int64(val))
+}
+
+// Int64 is an int64 field.
+func Int64(key string, val int64) Field {
+	return Field{Key: key, typ: int64Type, integer: val}
+}
+
+// Object is a field of a log.ObjectMarshaler.
+func Object(key string, val log.ObjectMarshaler) Field {
+	return Field{Key: key, typ: objectType, iface: val}
+}
+
+// String is a string field.
+func String(key string, val string) Field {
+	return Field{Key: key, typ: stringType, str: val}
+}
+
+// Stringer is a field of the String method of val.
+func Stringer(key string, val fmt.Stringer) Field {
+	return Field{Key: key, typ: stringerType, iface: val}
+}
+
+// Strings is a []string field.
+func Strings(key string, val []string) Field {
+	return Field{Key: key, typ: stringsType, iface: val}
+}
+
+// Time is a time.Time field.
+func Time(key string, val time.Time) Field {
+	return Field{Key: key, typ: timeType, iface: val}
+}
+
+// Uint is a uint field.
+func Uint(key string, val uint) Field {
+	return Uint64(key, uint64(val))
+}
+
+// Uint8 is a uint8 field.
+func Uint8(key string, val uint8) Field {
+	return Uint64(key, uint64(val))
+}
+
+// Uint16 is a uint16 field.
+func Uint16(key string, val uint16) Field {
+	return Uint64(key, uint64(val))
+}
+
+// Uint32 is a uint32 field.
+func Uint32(key string, val uint32) Field {
+	return Uint64(key, uint64(val))
+}
+
+// Uint64 is a uint64 field.
+func Uint64(key string, val uint64) Field {
+	return Field{Key: key, typ: uint64Type, integer: int64(val)}
+}
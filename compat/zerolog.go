@@ -0,0 +1,433 @@
+package compat
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+// ZerologLogger is a zerolog-style logger, its level methods start an Event whose methods map
+// one-to-one onto the methods of log.Entry, e.g.
+//
+//	logger.Info().Str("path", path).Dur("elapsed", elapsed).Msg("request served")
+type ZerologLogger struct {
+	logger *log.Logger
+}
+
+// NewZerologLogger returns a ZerologLogger writing to the logger.
+func NewZerologLogger(l *log.Logger) ZerologLogger {
+	return ZerologLogger{logger: skip(l, 1)}
+}
+
+// Trace starts a new event with trace level.
+func (z ZerologLogger) Trace() *Event {
+	return (*Event)(z.logger.Trace())
+}
+
+// Debug starts a new event with debug level.
+func (z ZerologLogger) Debug() *Event {
+	return (*Event)(z.logger.Debug())
+}
+
+// Info starts a new event with info level.
+func (z ZerologLogger) Info() *Event {
+	return (*Event)(z.logger.Info())
+}
+
+// Warn starts a new event with warn level.
+func (z ZerologLogger) Warn() *Event {
+	return (*Event)(z.logger.Warn())
+}
+
+// Error starts a new event with error level.
+func (z ZerologLogger) Error() *Event {
+	return (*Event)(z.logger.Error())
+}
+
+// Fatal starts a new event with fatal level, Msg calls os.Exit(255).
+func (z ZerologLogger) Fatal() *Event {
+	return (*Event)(z.logger.Fatal())
+}
+
+// Panic starts a new event with panic level, Msg panics.
+func (z ZerologLogger) Panic() *Event {
+	return (*Event)(z.logger.Panic())
+}
+
+// Log starts a new event with no level.
+func (z ZerologLogger) Log() *Event {
+	return (*Event)(z.logger.Log())
+}
+
+// WithLevel starts a new event with level.
+func (z ZerologLogger) WithLevel(level log.Level) *Event {
+	return (*Event)(z.logger.WithLevel(level))
+}
+
+// Err starts a new event with error level and err as a field if err is not nil, otherwise with info level.
+func (z ZerologLogger) Err(err error) *Event {
+	return (*Event)(z.logger.Err(err))
+}
+
+// Print sends an event with no level. Arguments are handled in the manner of fmt.Print.
+func (z ZerologLogger) Print(v ...interface{}) {
+	z.logger.Printf("%s", fmt.Sprint(v...))
+}
+
+// Printf sends an event with no level. Arguments are handled in the manner of fmt.Printf.
+func (z ZerologLogger) Printf(format string, v ...interface{}) {
+	z.logger.Printf(format, v...)
+}
+
+// Level returns a ZerologLogger with the minimum level.
+func (z ZerologLogger) Level(level log.Level) ZerologLogger {
+	l := *z.logger
+	l.Level = level
+	return ZerologLogger{logger: &l}
+}
+
+// GetLevel returns the minimum level of the logger.
+func (z ZerologLogger) GetLevel() log.Level {
+	return z.logger.Level
+}
+
+// With starts a ZerologContext of contextual fields, Logger returns a ZerologLogger with them.
+//
+//	logger = logger.With().Str("component", "billing").Logger()
+func (z ZerologLogger) With() ZerologContext {
+	return ZerologContext{logger: z.logger, event: Dict()}
+}
+
+// ZerologContext builds the contextual fields of a ZerologLogger.
+type ZerologContext struct {
+	logger *log.Logger
+	event  *Event
+}
+
+// Logger returns a ZerologLogger with the contextual fields.
+func (c ZerologContext) Logger() ZerologLogger {
+	return ZerologLogger{logger: with(c.logger, (*log.Entry)(c.event).Value())}
+}
+
+// Fields adds the fields of a map[string]interface{} or of a []interface{} of key value pairs.
+func (c ZerologContext) Fields(fields interface{}) ZerologContext {
+	c.event.Fields(fields)
+	return c
+}
+
+// Str adds the field key with val as a string.
+func (c ZerologContext) Str(key, val string) ZerologContext {
+	c.event.Str(key, val)
+	return c
+}
+
+// Strs adds the field key with vals as a []string.
+func (c ZerologContext) Strs(key string, vals []string) ZerologContext {
+	c.event.Strs(key, vals)
+	return c
+}
+
+// Int adds the field key with i as an int.
+func (c ZerologContext) Int(key string, i int) ZerologContext {
+	c.event.Int(key, i)
+	return c
+}
+
+// Int64 adds the field key with i as an int64.
+func (c ZerologContext) Int64(key string, i int64) ZerologContext {
+	c.event.Int64(key, i)
+	return c
+}
+
+// Uint64 adds the field key with i as a uint64.
+func (c ZerologContext) Uint64(key string, i uint64) ZerologContext {
+	c.event.Uint64(key, i)
+	return c
+}
+
+// Float64 adds the field key with f as a float64.
+func (c ZerologContext) Float64(key string, f float64) ZerologContext {
+	c.event.Float64(key, f)
+	return c
+}
+
+// Bool adds the field key with b as a bool.
+func (c ZerologContext) Bool(key string, b bool) ZerologContext {
+	c.event.Bool(key, b)
+	return c
+}
+
+// Dur adds the field key with duration d.
+func (c ZerologContext) Dur(key string, d time.Duration) ZerologContext {
+	c.event.Dur(key, d)
+	return c
+}
+
+// Time adds the field key with t.
+func (c ZerologContext) Time(key string, t time.Time) ZerologContext {
+	c.event.Time(key, t)
+	return c
+}
+
+// Err adds the field "error" with err.
+func (c ZerologContext) Err(err error) ZerologContext {
+	c.event.Err(err)
+	return c
+}
+
+// Interface adds the field key with i marshaled using reflection.
+func (c ZerologContext) Interface(key string, i interface{}) ZerologContext {
+	c.event.Interface(key, i)
+	return c
+}
+
+// Timestamp is a no-op, the entries of log.Logger always have a timestamp.
+func (c ZerologContext) Timestamp() ZerologContext {
+	return c
+}
+
+// Caller is a no-op, the caller is set by log.Logger.Caller.
+func (c ZerologContext) Caller() ZerologContext {
+	return c
+}
+
+// Event is a zerolog-style event, a log.Entry.
+type Event log.Entry
+
+// Dict starts a sub dictionary for Event.Dict.
+func Dict() *Event {
+	return (*Event)(log.NewContext(nil))
+}
+
+// Enabled returns false if the event is going to be filtered out by log level.
+func (e *Event) Enabled() bool {
+	return (*log.Entry)(e).Enabled()
+}
+
+// Discard disables the event so Msg(f) won't print it.
+func (e *Event) Discard() *Event {
+	return (*Event)((*log.Entry)(e).Discard())
+}
+
+// Msg sends the event with msg as the message field.
+func (e *Event) Msg(msg string) {
+	(*log.Entry)(e).Msg(msg)
+}
+
+// Msgf sends the event with formatted msg as the message field.
+func (e *Event) Msgf(format string, v ...interface{}) {
+	(*log.Entry)(e).Msgf(format, v...)
+}
+
+// Send sends the event without a message.
+func (e *Event) Send() {
+	(*log.Entry)(e).Msg("")
+}
+
+// Timestamp is a no-op, the entries of log.Logger always have a timestamp.
+func (e *Event) Timestamp() *Event {
+	return e
+}
+
+// Caller adds the file:line of the "caller" key, skip is the number of extra frames to skip.
+func (e *Event) Caller(skip ...int) *Event {
+	depth := 2
+	if len(skip) > 0 {
+		depth += skip[0]
+	}
+	return (*Event)((*log.Entry)(e).Caller(depth))
+}
+
+// Stack enables stack trace printing for the error passed to Err().
+func (e *Event) Stack() *Event {
+	return (*Event)((*log.Entry)(e).Stack())
+}
+
+// Fields adds the fields of a map[string]interface{} or of a []interface{} of key value pairs.
+func (e *Event) Fields(fields interface{}) *Event {
+	switch fields := fields.(type) {
+	case map[string]interface{}:
+		return (*Event)((*log.Entry)(e).Fields(fields))
+	case []interface{}:
+		return (*Event)((*log.Entry)(e).KeysAndValues(fields...))
+	}
+	return e
+}
+
+// Dict adds the field key with the fields of a dictionary started by Dict.
+func (e *Event) Dict(key string, dict *Event) *Event {
+	return (*Event)((*log.Entry)(e).Dict(key, (*log.Entry)(dict).Value()))
+}
+
+// Func calls f with the event if it is enabled.
+func (e *Event) Func(f func(e *Event)) *Event {
+	if e != nil {
+		f(e)
+	}
+	return e
+}
+
+// Str adds the field key with val as a string.
+func (e *Event) Str(key, val string) *Event {
+	return (*Event)((*log.Entry)(e).Str(key, val))
+}
+
+// Strs adds the field key with vals as a []string.
+func (e *Event) Strs(key string, vals []string) *Event {
+	return (*Event)((*log.Entry)(e).Strs(key, vals))
+}
+
+// Stringer adds the field key with val.String().
+func (e *Event) Stringer(key string, val fmt.Stringer) *Event {
+	return (*Event)((*log.Entry)(e).Stringer(key, val))
+}
+
+// Bytes adds the field key with val as a string.
+func (e *Event) Bytes(key string, val []byte) *Event {
+	return (*Event)((*log.Entry)(e).Bytes(key, val))
+}
+
+// Hex adds the field key with val as a hex string.
+func (e *Event) Hex(key string, val []byte) *Event {
+	return (*Event)((*log.Entry)(e).Hex(key, val))
+}
+
+// RawJSON adds already encoded JSON under key.
+func (e *Event) RawJSON(key string, b []byte) *Event {
+	return (*Event)((*log.Entry)(e).RawJSON(key, b))
+}
+
+// Err adds the field "error" with err.
+func (e *Event) Err(err error) *Event {
+	return (*Event)((*log.Entry)(e).Err(err))
+}
+
+// AnErr adds the field key with err.
+func (e *Event) AnErr(key string, err error) *Event {
+	return (*Event)((*log.Entry)(e).AnErr(key, err))
+}
+
+// Errs adds the field key with errs as an array of errors.
+func (e *Event) Errs(key string, errs []error) *Event {
+	return (*Event)((*log.Entry)(e).Errs(key, errs))
+}
+
+// Bool adds the field key with b as a bool.
+func (e *Event) Bool(key string, b bool) *Event {
+	return (*Event)((*log.Entry)(e).Bool(key, b))
+}
+
+// Bools adds the field key with b as a []bool.
+func (e *Event) Bools(key string, b []bool) *Event {
+	return (*Event)((*log.Entry)(e).Bools(key, b))
+}
+
+// Int adds the field key with i as an int.
+func (e *Event) Int(key string, i int) *Event {
+	return (*Event)((*log.Entry)(e).Int(key, i))
+}
+
+// Int8 adds the field key with i as an int8.
+func (e *Event) Int8(key string, i int8) *Event {
+	return (*Event)((*log.Entry)(e).Int8(key, i))
+}
+
+// Int16 adds the field key with i as an int16.
+func (e *Event) Int16(key string, i int16) *Event {
+	return (*Event)((*log.Entry)(e).Int16(key, i))
+}
+
+// Int32 adds the field key with i as an int32.
+func (e *Event) Int32(key string, i int32) *Event {
+	return (*Event)((*log.Entry)(e).Int32(key, i))
+}
+
+// Int64 adds the field key with i as an int64.
+func (e *Event) Int64(key string, i int64) *Event {
+	return (*Event)((*log.Entry)(e).Int64(key, i))
+}
+
+// Ints adds the field key with i as a []int.
+func (e *Event) Ints(key string, i []int) *Event {
+	return (*Event)((*log.Entry)(e).Ints(key, i))
+}
+
+// Uint adds the field key with i as a uint.
+func (e *Event) Uint(key string, i uint) *Event {
+	return (*Event)((*log.Entry)(e).Uint(key, i))
+}
+
+// Uint8 adds the field key with i as a uint8.
+func (e *Event) Uint8(key string, i uint8) *Event {
+	return (*Event)((*log.Entry)(e).Uint8(key, i))
+}
+
+// Uint16 adds the field key with i as a uint16.
+func (e *Event) Uint16(key string, i uint16) *Event {
+	return (*Event)((*log.Entry)(e).Uint16(key, i))
+}
+
+// Uint32 adds the field key with i as a uint32.
+func (e *Event) Uint32(key string, i uint32) *Event {
+	return (*Event)((*log.Entry)(e).Uint32(key, i))
+}
+
+// Uint64 adds the field key with i as a uint64.
+func (e *Event) Uint64(key string, i uint64) *Event {
+	return (*Event)((*log.Entry)(e).Uint64(key, i))
+}
+
+// Float32 adds the field key with f as a float32.
+func (e *Event) Float32(key string, f float32) *Event {
+	return (*Event)((*log.Entry)(e).Float32(key, f))
+}
+
+// Float64 adds the field key with f as a float64.
+func (e *Event) Float64(key string, f float64) *Event {
+	return (*Event)((*log.Entry)(e).Float64(key, f))
+}
+
+// Dur adds the field key with duration d.
+func (e *Event) Dur(key string, d time.Duration) *Event {
+	return (*Event)((*log.Entry)(e).Dur(key, d))
+}
+
+// TimeDiff adds the field key with the positive duration between time t and start.
+func (e *Event) TimeDiff(key string, t time.Time, start time.Time) *Event {
+	return (*Event)((*log.Entry)(e).TimeDiff(key, t, start))
+}
+
+// Time adds the field key with t.
+func (e *Event) Time(key string, t time.Time) *Event {
+	return (*Event)((*log.Entry)(e).Time(key, t))
+}
+
+// IPAddr adds the field key with ip as an IPv4 or IPv6 address.
+func (e *Event) IPAddr(key string, ip net.IP) *Event {
+	return (*Event)((*log.Entry)(e).IPAddr(key, ip))
+}
+
+// MACAddr adds the field key with ha as a MAC address.
+func (e *Event) MACAddr(key string, ha net.HardwareAddr) *Event {
+	return (*Event)((*log.Entry)(e).MACAddr(key, ha))
+}
+
+// Interface adds the field key with i marshaled using reflection.
+func (e *Event) Interface(key string, i interface{}) *Event {
+	return (*Event)((*log.Entry)(e).Interface(key, i))
+}
+
+// Any adds the field key with i, in the manner of log.Entry.Any.
+func (e *Event) Any(key string, i interface{}) *Event {
+	if e == nil {
+		return nil
+	}
+	return (*Event)((*log.Entry)(e).Any(key, i))
+}
+
+// Object adds the field key with obj marshaled by its MarshalObject method.
+func (e *Event) Object(key string, obj log.ObjectMarshaler) *Event {
+	return (*Event)((*log.Entry)(e).Object(key, obj))
+}
@@ -5,6 +5,8 @@ import (
 	"io"
 	"runtime"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // IsTerminal returns whether the given file descriptor is a terminal.
@@ -36,6 +38,15 @@ type ConsoleWriter struct {
 	// If it is set, ColorOutput, QuoteString and EndWithMessage will be ignore.
 	Formatter func(w io.Writer, args *FormatterArgs) (n int, err error)
 
+	// Aliases specifies the optional keys of well-known fields, e.g. ZapFieldAliases,
+	// for rendering the JSON output of other logging libraries.
+	Aliases *FieldAliases
+
+	// TrustedFields specifies the keys of fields rendered verbatim, e.g. pre-colored output.
+	// The control characters and escape sequences in the other keys and values are replaced
+	// with visible escapes, so that a logged value cannot rewrite the terminal.
+	TrustedFields []string
+
 	// Writer is the output destination. using os.Stderr if empty.
 	Writer io.Writer
 }
@@ -50,7 +61,16 @@ func (w *ConsoleWriter) Close() (err error) {
 	return
 }
 
+// Write implements io.Writer, it parses a JSON log line and writes it in the formatted output.
+func (w *ConsoleWriter) Write(p []byte) (int, error) {
+	return w.WriteEntry(&Entry{buf: p})
+}
+
 func (w *ConsoleWriter) write(out io.Writer, p []byte) (int, error) {
+	if IsSegmentMarker(p) {
+		return len(p), nil
+	}
+
 	b := bbpool.Get().(*bb)
 	b.B = b.B[:0]
 	defer bbpool.Put(b)
@@ -58,14 +78,20 @@ func (w *ConsoleWriter) write(out io.Writer, p []byte) (int, error) {
 	b.B = append(b.B, p...)
 
 	var args FormatterArgs
-	parseFormatterArgs(b.B, &args)
+	parseFormatterArgsWithAliases(b.B, &args, w.Aliases)
 
 	switch {
 	case args.Time == "":
+		if s := escapeTerminal(b2s(p), true); len(s) != len(p) {
+			_, err := io.WriteString(out, s)
+			return len(p), err
+		}
 		return out.Write(p)
 	case w.Formatter != nil:
+		w.escape(&args, false)
 		return w.Formatter(out, &args)
 	default:
+		w.escape(&args, w.QuoteString)
 		return w.format(out, &args)
 	}
 
@@ -89,6 +115,15 @@ func (w *ConsoleWriter) format(out io.Writer, args *FormatterArgs) (n int, err e
 		Gray    = "\x1b[90m"
 	)
 
+	// quote the string values, strconv.Quote escapes the control characters
+	if w.QuoteString {
+		for i := range args.KeyValues {
+			if kv := &args.KeyValues[i]; kv.ValueType == 's' && !w.trusted(kv.Key) {
+				kv.Value = strconv.Quote(kv.Value)
+			}
+		}
+	}
+
 	// colorful level string
 	var color, three string
 	switch args.Level {
@@ -124,9 +159,6 @@ func (w *ConsoleWriter) format(out io.Writer, args *FormatterArgs) (n int, err e
 		}
 		// key and values
 		for _, kv := range args.KeyValues {
-			if w.QuoteString && kv.ValueType == 's' {
-				kv.Value = strconv.Quote(kv.Value)
-			}
 			if kv.Key == "error" {
 				fmt.Fprintf(b, " %s%s=%s%s", Red, kv.Key, kv.Value, Reset)
 			} else {
@@ -150,11 +182,7 @@ func (w *ConsoleWriter) format(out io.Writer, args *FormatterArgs) (n int, err e
 		}
 		// key and values
 		for _, kv := range args.KeyValues {
-			if w.QuoteString && kv.ValueType == 's' {
-				fmt.Fprintf(b, " %s=%s", kv.Key, strconv.Quote(kv.Value))
-			} else {
-				fmt.Fprintf(b, " %s=%s", kv.Key, kv.Value)
-			}
+			fmt.Fprintf(b, " %s=%s", kv.Key, kv.Value)
 		}
 		// message
 		if w.EndWithMessage {
@@ -184,6 +212,7 @@ func (f LogfmtFormatter) Formatter(out io.Writer, args *FormatterArgs) (n int, e
 	b.B = b.B[:0]
 	defer bbpool.Put(b)
 
+	// the args are escaped by ConsoleWriter
 	fmt.Fprintf(b, "%s=%s ", f.TimeField, args.Time)
 	if args.Level != "" && args.Level[0] != '?' {
 		fmt.Fprintf(b, "level=%s ", args.Level)
@@ -203,8 +232,6 @@ func (f LogfmtFormatter) Formatter(out io.Writer, args *FormatterArgs) (n int, e
 			fmt.Fprintf(b, "%s=false ", kv.Key)
 		case 'n':
 			fmt.Fprintf(b, "%s=%s ", kv.Key, kv.Value)
-		case 'S':
-			fmt.Fprintf(b, "%s=%s ", kv.Key, kv.Value)
 		case 's':
 			fmt.Fprintf(b, "%s=%s ", kv.Key, strconv.Quote(kv.Value))
 		default:
@@ -217,4 +244,109 @@ func (f LogfmtFormatter) Formatter(out io.Writer, args *FormatterArgs) (n int, e
 	return out.Write(b.B)
 }
 
+// escape neutralises the control characters and escape sequences of the args except TrustedFields,
+// before any formatter runs. The string values are left to the formatter if quoted.
+func (w *ConsoleWriter) escape(args *FormatterArgs, quoted bool) {
+	args.Message = strings.TrimSuffix(args.Message, "\n")
+	for _, field := range [...]struct {
+		key string
+		s   *string
+	}{
+		{"time", &args.Time},
+		{"level", &args.Level},
+		{"goid", &args.Goid},
+		{"caller", &args.Caller},
+		{"message", &args.Message},
+	} {
+		if !w.trusted(field.key) {
+			*field.s = escapeTerminal(*field.s, false)
+		}
+	}
+	if !w.trusted("stack") {
+		args.Stack = escapeTerminal(args.Stack, true)
+	}
+	for i := range args.KeyValues {
+		kv := &args.KeyValues[i]
+		if w.trusted(kv.Key) {
+			continue
+		}
+		if !quoted || kv.ValueType != 's' {
+			kv.Value = escapeTerminal(kv.Value, false)
+		}
+		kv.Key = escapeTerminal(kv.Key, false)
+	}
+}
+
+func (w *ConsoleWriter) trusted(key string) bool {
+	for _, k := range w.TrustedFields {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeTerminal replaces the characters which a terminal may interpret with visible escapes,
+// the C0 and C1 control characters except tab, DEL, invalid UTF-8 bytes, and the bidirectional
+// and line separator characters. Newlines are kept if multiline is true.
+func escapeTerminal(s string, multiline bool) string {
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if terminalUnsafe(rune(c), multiline) {
+				break
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if (r == utf8.RuneError && size == 1) || terminalUnsafe(r, multiline) {
+			break
+		}
+		i += size
+	}
+	if i == len(s) {
+		return s
+	}
+
+	const hexdigits = "0123456789abcdef"
+	b := make([]byte, 0, len(s)+16)
+	b = append(b, s[:i]...)
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b = append(b, '\\', 'x', hexdigits[s[i]>>4], hexdigits[s[i]&0xf])
+		case !terminalUnsafe(r, multiline):
+			b = append(b, s[i:i+size]...)
+		case r == '\n':
+			b = append(b, '\\', 'n')
+		case r == '\r':
+			b = append(b, '\\', 'r')
+		case r < 0x80:
+			b = append(b, '\\', 'x', hexdigits[r>>4], hexdigits[r&0xf])
+		default:
+			b = append(b, '\\', 'u', hexdigits[r>>12&0xf], hexdigits[r>>8&0xf], hexdigits[r>>4&0xf], hexdigits[r&0xf])
+		}
+		i += size
+	}
+	return b2s(b)
+}
+
+func terminalUnsafe(r rune, multiline bool) bool {
+	switch {
+	case r == '\t':
+		return false
+	case r == '\n':
+		return !multiline
+	case r < 0x20, r >= 0x7f && r <= 0x9f:
+		return true
+	case r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069, r == 0x2028, r == 0x2029:
+		return true
+	}
+	return false
+}
+
 var _ Writer = (*ConsoleWriter)(nil)
+var _ io.Writer = (*ConsoleWriter)(nil)
@@ -1,11 +1,13 @@
 package log
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -321,3 +323,123 @@ func TestConsoleWriterLogfmt(t *testing.T) {
 		KeysAndValues("foo", "bar", "number", 42).
 		Msg("aaaa 'b' cccc")
 }
+
+func TestConsoleWriterEscape(t *testing.T) {
+	cases := []struct {
+		JSON string
+		Want string
+	}{
+		{
+			`{"time":"2019-07-10T05:35:54.277Z","level":"info","message":"\u001b[31mred\u001b[0m \u001b[2J\u001b[H"}`,
+			`2019-07-10T05:35:54.277Z INF > \x1b[31mred\x1b[0m \x1b[2J\x1b[H` + "\n",
+		},
+		{
+			`{"time":"2019-07-10T05:35:54.277Z","level":"info","user":"admin\rguest","message":"login"}`,
+			`2019-07-10T05:35:54.277Z INF > login user=admin\rguest` + "\n",
+		},
+		{
+			`{"time":"2019-07-10T05:35:54.277Z","level":"info","message":"\u001b]0;owned\u0007title"}`,
+			`2019-07-10T05:35:54.277Z INF > \x1b]0;owned\x07title` + "\n",
+		},
+		{
+			`{"time":"2019-07-10T05:35:54.277Z","level":"info","message":"ok\n2019-07-10T05:35:55.000Z ERR > fake entry"}`,
+			`2019-07-10T05:35:54.277Z INF > ok\n2019-07-10T05:35:55.000Z ERR > fake entry` + "\n",
+		},
+		{
+			`{"time":"2019-07-10T05:35:54.277Z","level":"info","k` + "\u009b" + `2Jey":"v\u009b31m","message":"c1 \u202egnp.exe"}`,
+			`2019-07-10T05:35:54.277Z INF > c1 \u202egnp.exe k\u009b2Jey=v\u009b31m` + "\n",
+		},
+		{
+			`{"time":"2019-07-10T05:35:54.277Z","level":"info","stack":"stack1\n\tstack2\u001b[2K\n","message":"multiline stack"}`,
+			`2019-07-10T05:35:54.277Z INF > multiline stack` + "\nstack1\n\tstack2\\x1b[2K\n",
+		},
+		{
+			"\x1b[2Jnot a json line\r\n",
+			`\x1b[2Jnot a json line\r` + "\n",
+		},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		w := &ConsoleWriter{Writer: &buf}
+		if _, err := wlprintf(w, InfoLevel, "%s", c.JSON); err != nil {
+			t.Errorf("test escape console writer error: %+v", err)
+		}
+		if got := buf.String(); got != c.Want {
+			t.Errorf("test escape console writer want=%q got=%q", c.Want, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := &ConsoleWriter{
+		QuoteString:   true,
+		TrustedFields: []string{"status"},
+		Writer:        &buf,
+	}
+	_, err := wlprintf(w, InfoLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"info","status":"\u001b[32mok\u001b[0m","path":"/\u001b[2J","message":"request"}`)
+	if err != nil {
+		t.Errorf("test trusted console writer error: %+v", err)
+	}
+	want := "2019-07-10T05:35:54.277Z INF > request status=\x1b[32mok\x1b[0m path=\"/\\x1b[2J\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("test trusted console writer want=%q got=%q", want, got)
+	}
+
+	buf.Reset()
+	w = &ConsoleWriter{Formatter: LogfmtFormatter{"time"}.Formatter, Writer: &buf}
+	_, err = wlprintf(w, InfoLevel, `{"time":"2019-07-10T05:35:54.277Z","level":"info","k`+"\u009b"+`ey":"value\r","message":"\u001b[2J"}`)
+	if err != nil {
+		t.Errorf("test logfmt console writer error: %+v", err)
+	}
+	want = `time=2019-07-10T05:35:54.277Z level=info k\u009bey="value\\r" "\\x1b[2J"` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("test logfmt console writer want=%q got=%q", want, got)
+	}
+}
+
+func TestConsoleWriterEscapeFormatter(t *testing.T) {
+	const line = `{"time":"2019-07-10T05:35:54.277Z\u001b[H","level":"info\u001b[2J","caller":"a.go:1\u0007","k\u001bey":"v\u001b[31m","n":1,"message":"m\u001b[0m"}`
+
+	var buf bytes.Buffer
+	w := &ConsoleWriter{Formatter: LogfmtFormatter{"time"}.Formatter, Writer: &buf}
+	if _, err := wlprintf(w, InfoLevel, "%s", line); err != nil {
+		t.Errorf("test logfmt console writer error: %+v", err)
+	}
+	if got := buf.String(); strings.Contains(got, "\x1b") || !strings.Contains(got, `level=info\x1b[2J `) {
+		t.Errorf("test logfmt console writer should escape the level: %q", got)
+	}
+
+	// a custom formatter gets the escaped args
+	buf.Reset()
+	w = &ConsoleWriter{
+		Formatter: func(w io.Writer, a *FormatterArgs) (int, error) {
+			return fmt.Fprintf(w, "%s|%s|%s|%s|%s=%s\n", a.Time, a.Level, a.Caller, a.Message, a.KeyValues[0].Key, a.KeyValues[0].Value)
+		},
+		Writer: &buf,
+	}
+	if _, err := wlprintf(w, InfoLevel, "%s", line); err != nil {
+		t.Errorf("test custom console writer error: %+v", err)
+	}
+	want := `2019-07-10T05:35:54.277Z\x1b[H|info\x1b[2J|a.go:1\x07|m\x1b[0m|k\u001bey=v\x1b[31m` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("test custom console writer want=%q got=%q", want, got)
+	}
+}
+
+func TestConsoleWriterAliases(t *testing.T) {
+	var buf bytes.Buffer
+	w := &ConsoleWriter{
+		Aliases: ZapFieldAliases,
+		Writer:  &buf,
+	}
+
+	_, err := wlprintf(w, InfoLevel, `{"level":"warn","ts":1573071825.123,"caller":"main.go:42","msg":"hello zap","foo":"bar"}`+"\n")
+	if err != nil {
+		t.Errorf("test zap console writer error: %+v", err)
+	}
+
+	want := time.Unix(1573071825, 123000000).Format("2006-01-02T15:04:05.999Z07:00") + " WRN  main.go:42 > hello zap foo=bar\n"
+	if got := buf.String(); got != want {
+		t.Errorf("test zap console writer want=%q got=%q", want, got)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !logstack_debug
+// +build !logstack_debug
+
+package log
+
+// getEntry gets an entry from the pool.
+func getEntry() *Entry {
+	return epool.Get().(*Entry)
+}
+
+// putEntry returns a finished entry to the pool.
+func putEntry(e *Entry) {
+	if cap(e.buf) <= bbcap {
+		epool.Put(e)
+	}
+}
+
+// checkEntry validates the entry, it is only implemented by the logstack_debug build tag.
+func checkEntry(e *Entry) {}
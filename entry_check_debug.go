@@ -0,0 +1,103 @@
+//go:build logstack_debug
+// +build logstack_debug
+
+package log
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// In logstack_debug builds entries are never pooled, the state of every entry is kept
+// in a side table to detect uses after Msg or Discard, and leaks detected by finalizers.
+
+type entryState struct {
+	created  string
+	finished string
+}
+
+var entryStates = struct {
+	sync.Mutex
+	m map[uintptr]*entryState
+}{m: make(map[uintptr]*entryState)}
+
+// packageDir is the directory of the package sources, its frames are skipped in reports.
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+func getEntry() *Entry {
+	e := &Entry{buf: make([]byte, 0, 1024)}
+
+	entryStates.Lock()
+	entryStates.m[entryKey(e)] = &entryState{created: entryCaller()}
+	entryStates.Unlock()
+
+	runtime.SetFinalizer(e, func(e *Entry) {
+		entryStates.Lock()
+		state := entryStates.m[entryKey(e)]
+		delete(entryStates.m, entryKey(e))
+		entryStates.Unlock()
+
+		if state != nil && state.finished == "" {
+			panic("logstack: entry created at " + state.created + " was never finished by Msg or Discard")
+		}
+	})
+
+	return e
+}
+
+func putEntry(e *Entry) {
+	entryStates.Lock()
+	if state := entryStates.m[entryKey(e)]; state != nil {
+		state.finished = entryCaller()
+	}
+	entryStates.Unlock()
+}
+
+func checkEntry(e *Entry) {
+	if e == nil {
+		return
+	}
+
+	entryStates.Lock()
+	state := entryStates.m[entryKey(e)]
+	entryStates.Unlock()
+
+	if state == nil || state.finished == "" {
+		return
+	}
+
+	method := "Entry"
+	if pc, _, _, ok := runtime.Caller(1); ok {
+		name := runtime.FuncForPC(pc).Name()
+		method = "Entry." + name[strings.LastIndexByte(name, '.')+1:]
+	}
+	panic(fmt.Sprintf("logstack: %s called at %s on an entry created at %s and already finished at %s",
+		method, entryCaller(), state.created, state.finished))
+}
+
+func entryKey(e *Entry) uintptr {
+	return uintptr(unsafe.Pointer(e))
+}
+
+// entryCaller returns the location of the first caller outside of the package sources.
+func entryCaller() string {
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != packageDir || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return "?"
+		}
+	}
+}
@@ -0,0 +1,78 @@
+//go:build logstack_debug
+// +build logstack_debug
+
+package log
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func expectEntryPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		r := recover()
+		if r == nil {
+			t.Fatalf("entry misuse should panic")
+		}
+		if msg, _ := r.(string); !strings.Contains(msg, want) || !strings.Contains(msg, "entry_check_debug_test.go:") {
+			t.Errorf("entry misuse panic want=%q got=%v", want, r)
+		}
+	}()
+	f()
+}
+
+func TestEntryCheckUseAfterMsg(t *testing.T) {
+	logger := Logger{Writer: IOWriter{io.Discard}}
+
+	e := logger.Info()
+	e.Msg("hello")
+	expectEntryPanic(t, "Entry.Str called at", func() {
+		e.Str("foo", "bar")
+	})
+}
+
+func TestEntryCheckDoubleMsg(t *testing.T) {
+	logger := Logger{Writer: IOWriter{io.Discard}}
+
+	e := logger.Info().Str("foo", "bar")
+	e.Msg("hello")
+	expectEntryPanic(t, "Entry.Msg called at", func() {
+		e.Msg("hello again")
+	})
+}
+
+func TestEntryCheckUseAfterDiscard(t *testing.T) {
+	logger := Logger{Writer: IOWriter{io.Discard}}
+
+	e := logger.Info()
+	e.Discard()
+	expectEntryPanic(t, "already finished at", func() {
+		e.Int("n", 42)
+	})
+}
+
+func TestEntryCheckLeak(t *testing.T) {
+	if os.Getenv("LOGSTACK_TEST_LEAK") == "1" {
+		logger := Logger{Writer: IOWriter{io.Discard}}
+		logger.Info().Str("foo", "bar")
+		for i := 0; i < 10; i++ {
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+		}
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEntryCheckLeak$")
+	cmd.Env = append(os.Environ(), "LOGSTACK_TEST_LEAK=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "was never finished by Msg or Discard") || !strings.Contains(string(out), "entry_check_debug_test.go:") {
+		t.Errorf("leaked entry should panic in finalizer, err=%v output=%s", err, out)
+	}
+}
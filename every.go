@@ -0,0 +1,222 @@
+package log
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Every limits the entries of the call site to one per interval, keyed on the program counter
+// of the call site and the optional keys. It returns nil if the entry is suppressed, otherwise
+// the entry has a "suppressed" key with the number of entries suppressed since the last one, if any.
+//
+//	for _, item := range items {
+//		log.Warn().Every(30*time.Second).Str("item", item.Name).Msg("item is stale")
+//	}
+//
+// The header of a suppressed entry has been encoded already, Logger.Every checks before it.
+func (e *Entry) Every(interval time.Duration, keys ...string) *Entry {
+	if e == nil {
+		return nil
+	}
+	checkEntry(e)
+
+	var rpc [1]uintptr
+	callers(1, rpc[:])
+	return e.limit(siteKey(rpc[0], keys), int64(interval))
+}
+
+// Once limits the entries of the call site to the first one, keyed on the program counter
+// of the call site and the optional keys. It returns nil if the entry is suppressed.
+//
+// The header of a suppressed entry has been encoded already, Logger.Once checks before it.
+func (e *Entry) Once(keys ...string) *Entry {
+	if e == nil {
+		return nil
+	}
+	checkEntry(e)
+
+	var rpc [1]uintptr
+	callers(1, rpc[:])
+	return e.limit(siteKey(rpc[0], keys), math.MaxInt64)
+}
+
+func (e *Entry) limit(key uint64, interval int64) *Entry {
+	n, ok := sites.get(key).allow(interval)
+	if !ok {
+		putEntry(e)
+		return nil
+	}
+	if n != 0 {
+		e.Uint64("suppressed", n)
+	}
+	return e
+}
+
+// LimitedLogger starts the entries of a Logger limited by call site, see Logger.Every and Logger.Once.
+type LimitedLogger struct {
+	logger   *Logger
+	interval int64
+	keys     []string
+}
+
+// Every returns a LimitedLogger whose entries are limited to one per interval for each call site
+// of its level methods, keyed on the program counter and the optional keys. Unlike Entry.Every,
+// a suppressed entry returns nil before its header is encoded.
+//
+//	for _, item := range items {
+//		logger.Every(30*time.Second).Warn().Str("item", item.Name).Msg("item is stale")
+//	}
+func (l *Logger) Every(interval time.Duration, keys ...string) LimitedLogger {
+	return LimitedLogger{logger: l, interval: int64(interval), keys: keys}
+}
+
+// Once returns a LimitedLogger whose entries are limited to the first one for each call site
+// of its level methods, keyed on the program counter and the optional keys.
+func (l *Logger) Once(keys ...string) LimitedLogger {
+	return LimitedLogger{logger: l, interval: math.MaxInt64, keys: keys}
+}
+
+// Trace starts a new message with trace level, or returns nil if it is suppressed.
+func (l LimitedLogger) Trace() *Entry { return l.entry(TraceLevel) }
+
+// Debug starts a new message with debug level, or returns nil if it is suppressed.
+func (l LimitedLogger) Debug() *Entry { return l.entry(DebugLevel) }
+
+// Info starts a new message with info level, or returns nil if it is suppressed.
+func (l LimitedLogger) Info() *Entry { return l.entry(InfoLevel) }
+
+// Warn starts a new message with warning level, or returns nil if it is suppressed.
+func (l LimitedLogger) Warn() *Entry { return l.entry(WarnLevel) }
+
+// Error starts a new message with error level, or returns nil if it is suppressed.
+func (l LimitedLogger) Error() *Entry { return l.entry(ErrorLevel) }
+
+// Fatal starts a new message with fatal level, or returns nil if it is suppressed.
+func (l LimitedLogger) Fatal() *Entry { return l.entry(FatalLevel) }
+
+// Panic starts a new message with panic level, or returns nil if it is suppressed.
+func (l LimitedLogger) Panic() *Entry { return l.entry(PanicLevel) }
+
+// WithLevel starts a new message with level, or returns nil if it is suppressed.
+func (l LimitedLogger) WithLevel(level Level) *Entry { return l.entry(level) }
+
+// entry checks the level and the call site of the level method before encoding the header.
+//
+//go:noinline
+func (l LimitedLogger) entry(level Level) *Entry {
+	logger := l.logger
+	if logger.silent(level) {
+		return nil
+	}
+
+	var pc [1]uintptr
+	callers(2, pc[:])
+	n, ok := sites.get(siteKey(pc[0], l.keys)).allow(l.interval)
+	if !ok {
+		return nil
+	}
+
+	e := logger.header(level)
+	if caller, full := logger.Caller, false; caller != 0 {
+		if caller < 0 {
+			caller, full = -caller, true
+		}
+		var rpc [1]uintptr
+		e.caller(callers(caller+1, rpc[:]), rpc[:], full)
+	}
+	if n != 0 {
+		e.Uint64("suppressed", n)
+	}
+	return e
+}
+
+// siteKey returns the non-zero hash of a call site and keys.
+func siteKey(pc uintptr, keys []string) uint64 {
+	h := uint64(pc)
+	for _, key := range keys {
+		h = h*1099511628211 ^ hashKey(key)
+	}
+	// murmur3 finalizer
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h | 1
+}
+
+// siteTableSize is the number of call sites tracked by Every and Once, a power of two.
+const siteTableSize = 4096
+
+// siteProbes is the number of slots probed for a call site before evicting one.
+const siteProbes = 8
+
+type site struct {
+	next       int64
+	suppressed uint64
+	key        uint64
+}
+
+// allow reports whether an entry of the site is allowed at the interval, with the number of
+// entries suppressed since the last allowed one.
+func (s *site) allow(interval int64) (uint64, bool) {
+	if s == nil {
+		// no slot is left for the site
+		return 0, true
+	}
+	now := timeNow().UnixNano()
+	for {
+		next := atomic.LoadInt64(&s.next)
+		if now < next {
+			atomic.AddUint64(&s.suppressed, 1)
+			return 0, false
+		}
+		deadline := now + interval
+		if interval == math.MaxInt64 || deadline < now {
+			deadline = math.MaxInt64
+		}
+		if atomic.CompareAndSwapInt64(&s.next, next, deadline) {
+			break
+		}
+	}
+	return atomic.SwapUint64(&s.suppressed, 0), true
+}
+
+// siteTable is a lock-free open addressing table of call sites of a fixed size. If the probed
+// slots are taken, the call site evicts the state of the probed site with the earliest deadline.
+// A site of Once is never evicted, so that it never fires twice. If all the probed slots are
+// held by sites of Once, the entries of the call site are not limited.
+type siteTable struct {
+	slots [siteTableSize]site
+}
+
+var sites siteTable
+
+func (t *siteTable) get(key uint64) *site {
+	i := key & (siteTableSize - 1)
+	var victim *site
+	for n := 0; n < siteProbes; n++ {
+		s := &t.slots[(i+uint64(n))&(siteTableSize-1)]
+		switch atomic.LoadUint64(&s.key) {
+		case key:
+			return s
+		case 0:
+			if atomic.CompareAndSwapUint64(&s.key, 0, key) || atomic.LoadUint64(&s.key) == key {
+				return s
+			}
+		}
+		if next := atomic.LoadInt64(&s.next); next != math.MaxInt64 && (victim == nil || next < atomic.LoadInt64(&victim.next)) {
+			victim = s
+		}
+	}
+
+	if victim == nil {
+		return nil
+	}
+	if old := atomic.LoadUint64(&victim.key); old != key && atomic.CompareAndSwapUint64(&victim.key, old, key) {
+		atomic.StoreInt64(&victim.next, 0)
+		atomic.StoreUint64(&victim.suppressed, 0)
+	}
+	return victim
+}
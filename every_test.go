@@ -0,0 +1,160 @@
+package log
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvery(t *testing.T) {
+	now := time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	var buf bytes.Buffer
+	logger := Logger{Writer: IOWriter{&buf}}
+
+	for i := 0; i < 5; i++ {
+		logger.Warn().Every(time.Minute).Int("i", i).Msg("every")
+		if i == 2 {
+			now = now.Add(time.Minute)
+		}
+	}
+	want := []string{`"i":0,"message":"every"}`, `"level":"warn","suppressed":2,"i":3,"message":"every"}`}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("every output not correct: %s", buf.String())
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("every line %d not correct: %s", i, lines[i])
+		}
+	}
+
+	// the keys separate the states of a call site
+	buf.Reset()
+	for i := 0; i < 3; i++ {
+		for _, key := range []string{"a", "b"} {
+			logger.Warn().Every(time.Minute, key).Str("key", key).Msg("keyed")
+		}
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("every keyed output not correct: %s", buf.String())
+	}
+}
+
+func TestOnce(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{Writer: IOWriter{&buf}}
+
+	for i := 0; i < 3; i++ {
+		logger.Warn().Once().Int("i", i).Msg("once")
+		logger.Warn().Once().Int("i", i).Msg("another call site")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], `"i":0,"message":"once"}`) || !strings.HasSuffix(lines[1], `"i":0,"message":"another call site"}`) {
+		t.Errorf("once output not correct: %s", buf.String())
+	}
+
+	var e *Entry
+	if e.Once() != nil || e.Every(time.Second) != nil {
+		t.Errorf("nil entry should stay nil")
+	}
+}
+
+func TestSiteTableEviction(t *testing.T) {
+	table := new(siteTable)
+	for key := uint64(1); key < 4*siteTableSize; key += 2 {
+		if s := table.get(key); s.key != key {
+			t.Fatalf("site table get(%d) returns slot of %d", key, s.key)
+		}
+	}
+}
+
+func TestLoggerEvery(t *testing.T) {
+	now := time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	var buf bytes.Buffer
+	logger := Logger{Level: WarnLevel, Caller: 1, Writer: IOWriter{&buf}}
+
+	for i := 0; i < 5; i++ {
+		// a disabled level does not take the turn of the call site
+		logger.Every(time.Minute).Info().Int("i", i).Msg("disabled")
+		logger.Every(time.Minute).Warn().Int("i", i).Msg("every")
+		if i == 2 {
+			now = now.Add(time.Minute)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], `"i":0,"message":"every"}`) || !strings.HasSuffix(lines[1], `"suppressed":2,"i":3,"message":"every"}`) {
+		t.Fatalf("logger every output not correct: %s", buf.String())
+	}
+	if !strings.Contains(lines[0], `"caller":"every_test.go:`) {
+		t.Errorf("logger every caller not correct: %s", lines[0])
+	}
+
+	buf.Reset()
+	for i := 0; i < 3; i++ {
+		logger.Once("a").Error().Int("i", i).Msg("once")
+		logger.Once("b").Error().Int("i", i).Msg("once")
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("logger once output not correct: %s", buf.String())
+	}
+}
+
+func TestSiteTableOnce(t *testing.T) {
+	table := new(siteTable)
+	// the keys of the same home slot
+	keys := make([]uint64, siteProbes+2)
+	for i := range keys {
+		keys[i] = uint64(i)*siteTableSize + 1
+	}
+
+	// an Every site is evicted instead of the Once sites
+	for _, key := range keys[:siteProbes-1] {
+		table.get(key).allow(math.MaxInt64)
+	}
+	table.get(keys[siteProbes-1]).allow(int64(time.Minute))
+	if s := table.get(keys[siteProbes]); s == nil || s.key != keys[siteProbes] {
+		t.Fatalf("site table should evict the Every site")
+	}
+	for _, key := range keys[:siteProbes-1] {
+		if _, ok := table.get(key).allow(math.MaxInt64); ok {
+			t.Errorf("once site %d fired twice", key)
+		}
+	}
+
+	// no Once site is evicted, the entries of the new site are not limited
+	table.get(keys[siteProbes]).allow(math.MaxInt64)
+	for i := 0; i < 2; i++ {
+		if _, ok := table.get(keys[siteProbes+1]).allow(math.MaxInt64); !ok {
+			t.Errorf("site without a slot should not be limited")
+		}
+	}
+	for _, key := range append(keys[:siteProbes-1:siteProbes-1], keys[siteProbes]) {
+		if _, ok := table.get(key).allow(math.MaxInt64); ok {
+			t.Errorf("once site %d fired twice", key)
+		}
+	}
+}
+
+func BenchmarkLoggerEvery(b *testing.B) {
+	logger := Logger{Writer: IOWriter{&bytes.Buffer{}}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Every(time.Hour).Warn().Str("foo", "bar").Msg("hello world")
+	}
+}
+
+func BenchmarkEvery(b *testing.B) {
+	logger := Logger{Writer: IOWriter{&bytes.Buffer{}}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Warn().Every(time.Hour).Str("foo", "bar").Msg("hello world")
+	}
+}
@@ -1,7 +1,9 @@
 package log
 
 import (
+	"compress/gzip"
 	"crypto/md5"
+	"crypto/rand"
 	"io"
 	"os"
 	"path/filepath"
@@ -21,7 +23,9 @@ import (
 // time.Time format of `2006-01-02T15-04-05` and the extension is the
 // original extension.  For example, if your FileWriter.Filename is
 // `/var/log/foo/server.log`, a backup created at 6:30pm on Nov 11 2016 would
-// use the filename `/var/log/foo/server.2016-11-04T18-30-00.log`
+// use the filename `/var/log/foo/server.2016-11-04T18-30-00.log`. If the
+// name is taken, e.g. by another rotation within the same second, a sequence
+// number follows the timestamp, as in `server.2016-11-04T18-30-00.1.log`.
 //
 // Cleaning Up Old Log Files
 //
@@ -29,7 +33,15 @@ import (
 // recent files according to filesystem modified time will be retained, up to a
 // number equal to MaxBackups (or all of them if MaxBackups is 0). Note that the
 // time encoded in the timestamp is the rotation time, which may differ from the
-// last time that file was written to.
+// last time that file was written to. The backups of the same modified time
+// are ordered by their sequence numbers.
+//
+// Companion Error Log File
+//
+// If ErrorFile is set, the entries at or above ErrorLevel are mirrored to
+// `name.error.ext`, e.g. `/var/log/foo/server.error.log`, which has its own
+// size, rotation and retention settings. Its backups are excluded from the
+// cleanup of the log file backups.
 type FileWriter struct {
 	// Filename is the file to write logs to.  Backup log files will be retained
 	// in the same directory.
@@ -46,6 +58,15 @@ type FileWriter struct {
 	mu   sync.Mutex
 	size int64
 	file *os.File
+	seg  segmentState
+
+	// the gzip stream of the active file and its compressed size
+	gz         *gzip.Writer
+	zsize      int64
+	flushTimer *time.Timer
+
+	// whether the settings of ErrorFile are resolved
+	companioned bool
 
 	// FileMode represents the file's mode and permission bits.  The default
 	// mode is 0644
@@ -74,6 +95,35 @@ type FileWriter struct {
 	// Cleaner specifies an optional cleanup function of log backups after rotation,
 	// if not set, the default behavior is to delete more than MaxBackups log files.
 	Cleaner func(filename string, maxBackups int, matches []os.FileInfo)
+
+	// ErrorFile specifies an optional writer of the companion error log file, its
+	// MaxSize, MaxBackups, TimeFormat, Header and Cleaner apply to the error file,
+	// and it is rotated independently, e.g. by ErrorFile.Rotate on another schedule.
+	// If its Filename is empty, it is set to `name.error.ext` and the HostName,
+	// ProcessID, EnsureFolder and FileMode are inherited when the log file is opened.
+	ErrorFile *FileWriter
+
+	// ErrorLevel specifies the minimum level of the entries mirrored to ErrorFile,
+	// the default is ErrorLevel.
+	ErrorLevel Level
+
+	// Segment specifies the optional metadata of the segment header and footer entries,
+	// which start and end every log file, see Segment.
+	Segment *Segment
+
+	// Gzip determines if the active log file is written through a streaming gzip writer
+	// as `name.timestamp.ext.gz`, the symlink is `name.ext.gz`. The stream is flushed
+	// every FlushInterval, so that readers, e.g. NewGzipReader, can decompress the written
+	// entries, and it ends on close or rotation.
+	Gzip bool
+
+	// FlushInterval specifies the maximum delay of the written entries in the gzip stream,
+	// the default is 1s.
+	FlushInterval time.Duration
+
+	// MaxSizeCompressed determines if MaxSize is accounted on the compressed bytes of Gzip,
+	// the default is the uncompressed bytes.
+	MaxSizeCompressed bool
 }
 
 // WriteEntry implements Writer.  If a write would cause the log file to be larger
@@ -82,13 +132,56 @@ type FileWriter struct {
 func (w *FileWriter) WriteEntry(e *Entry) (n int, err error) {
 	w.mu.Lock()
 	n, err = w.write(e.buf)
+	ew := w.errorFile(e.Level)
 	w.mu.Unlock()
+	if ew != nil {
+		if _, err1 := ew.WriteEntry(e); err == nil {
+			err = err1
+		}
+	}
 	return
 }
 
+// errorFile returns ErrorFile if the entries of the level are mirrored to it, it must be called under lock.
+func (w *FileWriter) errorFile(level Level) *FileWriter {
+	ew, threshold := w.ErrorFile, w.ErrorLevel
+	if threshold == 0 {
+		threshold = ErrorLevel
+	}
+	if ew == nil || w.Filename == "" || level < threshold || level > PanicLevel {
+		return nil
+	}
+	w.companion()
+	return ew
+}
+
+// companion sets the Filename of ErrorFile and the inherited settings once, it must be called under lock.
+// They are set under the lock of ErrorFile, which may be written and rotated concurrently.
+func (w *FileWriter) companion() {
+	ew := w.ErrorFile
+	if ew == nil || w.Filename == "" || w.companioned {
+		return
+	}
+	w.companioned = true
+
+	ew.mu.Lock()
+	if ew.Filename == "" {
+		ext := filepath.Ext(w.Filename)
+		ew.Filename = w.Filename[:len(w.Filename)-len(ext)] + ".error" + ext
+		ew.HostName = w.HostName
+		ew.ProcessID = w.ProcessID
+		ew.EnsureFolder = w.EnsureFolder
+		if ew.FileMode == 0 {
+			ew.FileMode = w.FileMode
+		}
+	}
+	ew.mu.Unlock()
+}
+
 // Write implements io.Writer.  If a write would cause the log file to be larger
 // than MaxSize, the file is closed, rotate to include a timestamp of the
 // current time, and update symlink with log name file to the new file.
+// The written entries are not mirrored to ErrorFile as their levels are unknown.
 func (w *FileWriter) Write(p []byte) (n int, err error) {
 	w.mu.Lock()
 	n, err = w.write(p)
@@ -114,28 +207,41 @@ func (w *FileWriter) write(p []byte) (n int, err error) {
 		}
 	}
 
-	n, err = w.file.Write(p)
+	n, err = w.fileWrite(p)
+	if w.Segment != nil {
+		w.seg.update(p[:n], timeNow())
+	}
 	if err != nil {
 		return
 	}
 
 	w.size += int64(n)
-	if w.MaxSize > 0 && w.size > w.MaxSize && w.Filename != "" {
+	size := w.size
+	if w.gz != nil && w.MaxSizeCompressed {
+		size = w.zsize
+	}
+	if w.MaxSize > 0 && size > w.MaxSize && w.Filename != "" {
 		err = w.rotate()
 	}
 
 	return
 }
 
-// Close implements io.Closer, and closes the current logfile.
+// Close implements io.Closer, and closes the current logfile and error logfile.
 func (w *FileWriter) Close() (err error) {
 	w.mu.Lock()
 	if w.file != nil {
-		err = w.file.Close()
+		err = w.closeFile()
 		w.file = nil
 		w.size = 0
 	}
+	ew := w.ErrorFile
 	w.mu.Unlock()
+	if ew != nil {
+		if err1 := ew.Close(); err == nil {
+			err = err1
+		}
+	}
 	return
 }
 
@@ -146,22 +252,36 @@ func (w *FileWriter) Close() (err error) {
 // files according to the configuration.
 func (w *FileWriter) Rotate() (err error) {
 	w.mu.Lock()
-	err = w.rotate()
+	if w.Filename != "" {
+		// an ErrorFile has no Filename until the log file is opened
+		err = w.rotate()
+	}
 	w.mu.Unlock()
 	return
 }
 
 func (w *FileWriter) rotate() (err error) {
+	w.companion()
+
 	var file *os.File
-	file, err = os.OpenFile(w.fileargs(timeNow()))
+	file, err = w.openNew(timeNow())
 	if err != nil {
 		return err
 	}
+	var previous string
 	if w.file != nil {
-		w.file.Close()
+		previous = filepath.Base(w.file.Name())
+		_ = w.closeFile()
 	}
 	w.file = file
 	w.size = 0
+	w.openGzip()
+
+	if w.Segment != nil {
+		if err = w.writeSegmentHeader(previous); err != nil {
+			return err
+		}
+	}
 
 	if w.Header != nil {
 		st, err := file.Stat()
@@ -169,8 +289,9 @@ func (w *FileWriter) rotate() (err error) {
 			return err
 		}
 		if b := w.Header(st); b != nil {
-			n, err := w.file.Write(b)
+			n, err := w.fileWrite(b)
 			w.size += int64(n)
+			w.seg.update(b[:n], time.Time{})
 			if err != nil {
 				return nil
 			}
@@ -178,15 +299,16 @@ func (w *FileWriter) rotate() (err error) {
 	}
 
 	go func(newname string) {
-		os.Remove(w.Filename)
+		link := w.linkname()
+		os.Remove(link)
 		if !w.ProcessID {
-			_ = os.Symlink(filepath.Base(newname), w.Filename)
+			_ = os.Symlink(filepath.Base(newname), link)
 		}
 
 		uid, _ := strconv.Atoi(os.Getenv("SUDO_UID"))
 		gid, _ := strconv.Atoi(os.Getenv("SUDO_GID"))
 		if uid != 0 && gid != 0 && os.Geteuid() == 0 {
-			_ = os.Lchown(w.Filename, uid, gid)
+			_ = os.Lchown(link, uid, gid)
 			_ = os.Chown(newname, uid, gid)
 		}
 
@@ -203,19 +325,23 @@ func (w *FileWriter) rotate() (err error) {
 
 		base, ext := filepath.Base(w.Filename), filepath.Ext(w.Filename)
 		prefix, extgz := base[:len(base)-len(ext)]+".", ext+".gz"
-		exclude := prefix + "error" + ext
+		exclude := prefix + "error."
 
 		matches := make([]os.FileInfo, 0)
 		for _, info := range infos {
 			name := info.Name()
-			if name != base && name != exclude &&
+			if name != base && name != filepath.Base(link) && !strings.HasPrefix(name, exclude) &&
 				strings.HasPrefix(name, prefix) &&
 				(strings.HasSuffix(name, ext) || strings.HasSuffix(name, extgz)) {
 				matches = append(matches, info)
 			}
 		}
 		sort.Slice(matches, func(i, j int) bool {
-			return matches[i].ModTime().Unix() < matches[j].ModTime().Unix()
+			ti, tj := matches[i].ModTime().Unix(), matches[j].ModTime().Unix()
+			if ti != tj {
+				return ti < tj
+			}
+			return w.backupSeq(matches[i].Name()) < w.backupSeq(matches[j].Name())
 		})
 
 		if w.Cleaner != nil {
@@ -230,8 +356,120 @@ func (w *FileWriter) rotate() (err error) {
 	return
 }
 
+// writeSegmentHeader starts a new segment by the header entry.
+func (w *FileWriter) writeSegmentHeader(previous string) error {
+	w.seg = segmentState{}
+	b := w.Segment.header(previous)
+	n, err := w.fileWrite(b)
+	w.size += int64(n)
+	w.seg.update(b[:n], time.Time{})
+	return err
+}
+
+// closeFile ends the segment and the gzip stream of the active file, and closes it.
+func (w *FileWriter) closeFile() (err error) {
+	if w.Segment != nil {
+		_, err = w.fileWrite(w.seg.footer())
+	}
+	if w.gz != nil {
+		if w.flushTimer != nil {
+			w.flushTimer.Stop()
+			w.flushTimer = nil
+		}
+		if err1 := w.gz.Close(); err == nil {
+			err = err1
+		}
+		w.gz = nil
+	}
+	if err1 := w.file.Close(); err == nil {
+		err = err1
+	}
+	return
+}
+
+// linkname returns the name of the symlink to the active file.
+func (w *FileWriter) linkname() string {
+	if w.Gzip {
+		return w.Filename + ".gz"
+	}
+	return w.Filename
+}
+
+// openNew creates a new log file of the given time. If the filename is taken, e.g. by a rotation
+// within the same second, a sequence number is appended to the timestamp, so that the file is always new.
+func (w *FileWriter) openNew(now time.Time) (*os.File, error) {
+	for seq := 0; ; seq++ {
+		filename, flag, perm := w.fileargs(now, seq)
+		other := filename + ".gz"
+		if w.Gzip {
+			filename, other = other, filename
+		}
+		if _, err := os.Lstat(other); err == nil {
+			continue
+		}
+		file, err := os.OpenFile(filename, flag|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		return file, err
+	}
+}
+
+// backupSeq returns the sequence number of a backup name, which is zero for the first file of a timestamp.
+func (w *FileWriter) backupSeq(name string) int {
+	base, ext := filepath.Base(w.Filename), filepath.Ext(w.Filename)
+	s := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+	s = strings.TrimPrefix(s, base[:len(base)-len(ext)]+".")
+	switch {
+	case w.HostName && w.ProcessID:
+		if i := strings.LastIndex(s, "."+hostname+"-"); i >= 0 {
+			s = s[:i]
+		}
+	case w.HostName:
+		s = strings.TrimSuffix(s, "."+hostname)
+	case w.ProcessID:
+		if i := strings.LastIndexByte(s, '.'); i >= 0 {
+			s = s[:i]
+		}
+	}
+
+	i := strings.LastIndexByte(s, '.')
+	if i < 0 {
+		return 0
+	}
+	seq, err := strconv.Atoi(s[i+1:])
+	if err != nil || seq <= 0 {
+		return 0
+	}
+	// the trailing number may be a part of the timestamp, e.g. of the format "2006.01.02"
+	timestamp := s[:i]
+	switch w.TimeFormat {
+	case "":
+		_, err = time.Parse("2006-01-02T15-04-05", timestamp)
+	case TimeFormatUnix, TimeFormatUnixMs:
+		_, err = strconv.ParseInt(timestamp, 10, 64)
+	default:
+		_, err = time.Parse(w.TimeFormat, timestamp)
+	}
+	if err != nil {
+		return 0
+	}
+	return seq
+}
+
 func (w *FileWriter) create() (err error) {
-	w.file, err = os.OpenFile(w.fileargs(timeNow()))
+	w.companion()
+
+	var previous string
+	if w.Segment != nil || w.Gzip {
+		// every segment or gzip stream is a new file, the symlink refers to the previous one
+		if link, err := os.Readlink(w.linkname()); err == nil {
+			previous = filepath.Base(link)
+		}
+		w.file, err = w.openNew(timeNow())
+	} else {
+		w.file, err = os.OpenFile(w.fileargs(timeNow(), 0))
+	}
 	if err != nil {
 		return err
 	}
@@ -240,27 +478,38 @@ func (w *FileWriter) create() (err error) {
 	if err == nil {
 		w.size = st.Size()
 	}
+	empty := w.size == 0
+	w.openGzip()
 
-	if w.size == 0 && w.Header != nil {
+	if w.Segment != nil {
+		if err = w.writeSegmentHeader(previous); err != nil {
+			return err
+		}
+	}
+
+	if empty && w.Header != nil {
 		if b := w.Header(st); b != nil {
-			n, err := w.file.Write(b)
+			n, err := w.fileWrite(b)
 			w.size += int64(n)
+			w.seg.update(b[:n], time.Time{})
 			if err != nil {
 				return err
 			}
 		}
 	}
 
-	os.Remove(w.Filename)
+	link := w.linkname()
+	os.Remove(link)
 	if !w.ProcessID {
-		_ = os.Symlink(filepath.Base(w.file.Name()), w.Filename)
+		_ = os.Symlink(filepath.Base(w.file.Name()), link)
 	}
 
 	return
 }
 
-// fileargs returns a new filename, flag, perm based on the original name and the given time.
-func (w *FileWriter) fileargs(now time.Time) (filename string, flag int, perm os.FileMode) {
+// fileargs returns a new filename, flag, perm based on the original name and the given time,
+// the timestamp of the filename is followed by the sequence number if it is not zero.
+func (w *FileWriter) fileargs(now time.Time, seq int) (filename string, flag int, perm os.FileMode) {
 	if !w.LocalTime {
 		now = now.UTC()
 	}
@@ -278,6 +527,9 @@ func (w *FileWriter) fileargs(now time.Time) (filename string, flag int, perm os
 	default:
 		filename = prefix + "." + now.Format(w.TimeFormat)
 	}
+	if seq != 0 {
+		filename += "." + strconv.Itoa(seq)
+	}
 	if w.HostName {
 		if w.ProcessID {
 			filename += "." + hostname + "-" + strconv.Itoa(pid) + ext
@@ -307,9 +559,6 @@ func (w *FileWriter) fileargs(now time.Time) (filename string, flag int, perm os
 var hostname, machine = func() (string, [16]byte) {
 	// host
 	host, err := os.Hostname()
-	if err != nil || strings.HasPrefix(host, "localhost") {
-		host = "localhost-" + strconv.FormatInt(int64(Fastrandn(1000000)), 10)
-	}
 	// seed files
 	var files []string
 	switch runtime.GOOS {
@@ -318,19 +567,38 @@ var hostname, machine = func() (string, [16]byte) {
 	case "freebsd":
 		files = []string{"/etc/hostid"}
 	}
-	// append seed to hostname
-	data := []byte(host)
+	var seed []byte
 	for _, file := range files {
 		if b, err := os.ReadFile(file); err == nil {
-			data = append(data, b...)
+			seed = append(seed, b...)
 		}
 	}
+	host = localHostname(host, err)
+	// append seed to hostname
+	data := append([]byte(host), seed...)
 	// md5 digest
 	hex := md5.Sum(data)
 
 	return host, hex
 }()
 
+// localHostname returns host, or a localhost name unique to the instance if host is unknown.
+// The suffix is derived from the pod UID, which is stable across restarts of a replica, otherwise
+// it is random, as the machine-id is often shared by the replicas of a container image.
+func localHostname(host string, err error) string {
+	if err == nil && !strings.HasPrefix(host, "localhost") {
+		return host
+	}
+	var b [3]byte
+	if id, err := XIDFromPodUID()(); err == nil {
+		copy(b[:], id[:3])
+	} else if _, err := rand.Read(b[:]); err != nil {
+		i := Fastrandn(1 << 24)
+		b = [3]byte{byte(i >> 16), byte(i >> 8), byte(i)}
+	}
+	return "localhost-" + strconv.FormatInt(int64(b[0])<<16|int64(b[1])<<8|int64(b[2]), 10)
+}
+
 var pid = os.Getpid()
 
 var _ Writer = (*FileWriter)(nil)
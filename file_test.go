@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -335,7 +338,7 @@ func TestFileWriterFileargs(t *testing.T) {
 	t.Run("neither hostname nor pid appears", func(t *testing.T) {
 		w := &FileWriter{Filename: filename}
 		expected := "file-output.2020-08-12T16-07-00.log"
-		if name, _, _ := w.fileargs(d); name != expected {
+		if name, _, _ := w.fileargs(d, 0); name != expected {
 			t.Fatalf("expected: %q, actual: %q", expected, name)
 		}
 	})
@@ -361,9 +364,197 @@ func TestFileWriterFileargs(t *testing.T) {
 		for _, c := range cases {
 			w.HostName = c.hostName
 			w.ProcessID = c.processID
-			if name, _, _ := w.fileargs(d); name != c.expected {
+			if name, _, _ := w.fileargs(d, 0); name != c.expected {
 				t.Fatalf("expected: %q, actual: %q", c.expected, name)
 			}
 		}
 	})
 }
+
+func TestFileWriterErrorFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+
+	now := time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	w := &FileWriter{
+		Filename:   filename,
+		MaxBackups: 1,
+		ErrorFile:  &FileWriter{MaxBackups: 5},
+	}
+	for _, level := range []Level{InfoLevel, ErrorLevel, WarnLevel, FatalLevel, noLevel} {
+		if _, err := wlprintf(w, level, "%s\n", level); err != nil {
+			t.Fatalf("file writer error: %+v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.error.log"))
+	if err != nil {
+		t.Fatalf("read error file error: %+v", err)
+	}
+	if string(data) != "error\nfatal\n" {
+		t.Errorf("error file content not correct: %q", data)
+	}
+	data, _ = os.ReadFile(filename)
+	if string(data) != "info\nerror\nwarn\nfatal\n????\n" {
+		t.Errorf("log file content not correct: %q", data)
+	}
+
+	// the error file rotates independently, its backups survive the cleanup of the log file
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Second)
+		_ = w.ErrorFile.Rotate()
+		time.Sleep(50 * time.Millisecond)
+	}
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Second)
+		_ = w.Rotate()
+		time.Sleep(50 * time.Millisecond)
+	}
+	w.ErrorLevel = WarnLevel
+	_, _ = wlprintf(w, WarnLevel, "warn again\n")
+	w.Close()
+
+	if matches, _ := filepath.Glob(filepath.Join(dir, "app.error.*.log")); len(matches) != 4 {
+		t.Errorf("error file backups not correct: %+v", matches)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "app.2019-*.log")); len(matches) != 2 {
+		t.Errorf("log file backups not correct: %+v", matches)
+	}
+	if data, _ = os.ReadFile(filepath.Join(dir, "app.error.log")); string(data) != "warn again\n" {
+		t.Errorf("error file content after rotation not correct: %q", data)
+	}
+}
+
+func TestFileWriterErrorFileConcurrentRotate(t *testing.T) {
+	dir := t.TempDir()
+
+	ew := &FileWriter{Cleaner: func(string, int, []os.FileInfo) {}}
+	w := &FileWriter{
+		Filename:  filepath.Join(dir, "app.log"),
+		ErrorFile: ew,
+		Cleaner:   func(string, int, []os.FileInfo) {},
+	}
+	defer w.Close()
+	// the settings of the error file are resolved when the log file is opened
+	_, _ = wlprintf(w, InfoLevel, "open\n")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_, _ = wlprintf(w, ErrorLevel, "error %d\n", i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			_ = ew.Rotate()
+		}
+	}()
+	wg.Wait()
+
+	if matches, _ := filepath.Glob(filepath.Join(dir, "app.error.*")); len(matches) == 0 {
+		t.Errorf("error file not written")
+	}
+}
+
+func TestFileWriterRotateSameSecond(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+
+	timeNow = func() time.Time { return time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
+
+	text := "hello file writer!\n"
+	w := &FileWriter{
+		Filename: filename,
+		MaxSize:  int64(len(text)),
+		Cleaner:  func(string, int, []os.FileInfo) {},
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := wlprintf(w, InfoLevel, "%d %s", i, text); err != nil {
+			t.Fatalf("file writer error: %+v", err)
+		}
+	}
+	w.Close()
+
+	// every rotation creates a new file with one entry
+	for i := 0; i <= 10; i++ {
+		name := filepath.Join(dir, "app.2019-07-10T05-35-54.log")
+		if i != 0 {
+			name = filepath.Join(dir, fmt.Sprintf("app.2019-07-10T05-35-54.%d.log", i))
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read file error: %+v", err)
+		}
+		if expected := fmt.Sprintf("%d %s", i, text); i == 10 {
+			if len(data) != 0 {
+				t.Errorf("file %s is not empty: %q", name, data)
+			}
+		} else if string(data) != expected {
+			t.Errorf("file %s content mismatch: data=[%s], text=[%s]", name, data, expected)
+		}
+	}
+
+	// a compressed backup takes the name too
+	if err := os.Rename(filepath.Join(dir, "app.2019-07-10T05-35-54.10.log"), filepath.Join(dir, "app.2019-07-10T05-35-54.10.log.gz")); err != nil {
+		t.Fatalf("rename error: %+v", err)
+	}
+	w = &FileWriter{Filename: filename, MaxBackups: 3}
+	_ = w.Rotate()
+	w.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	// the backups of the same second are retained by the sequence numbers
+	matches, _ := filepath.Glob(filepath.Join(dir, "app.2019-*"))
+	sort.Strings(matches)
+	expected := []string{
+		filepath.Join(dir, "app.2019-07-10T05-35-54.10.log.gz"),
+		filepath.Join(dir, "app.2019-07-10T05-35-54.11.log"),
+		filepath.Join(dir, "app.2019-07-10T05-35-54.8.log"),
+		filepath.Join(dir, "app.2019-07-10T05-35-54.9.log"),
+	}
+	if fmt.Sprint(matches) != fmt.Sprint(expected) {
+		t.Errorf("backups not correct: %+v", matches)
+	}
+}
+
+func TestFileWriterBackupSeq(t *testing.T) {
+	origHost, origPid := hostname, pid
+	hostname, pid = "shire", 198400
+	defer func() { hostname, pid = origHost, origPid }()
+
+	cases := []struct {
+		w    *FileWriter
+		name string
+		seq  int
+	}{
+		{&FileWriter{}, "app.2019-07-10T05-35-54.log", 0},
+		{&FileWriter{}, "app.2019-07-10T05-35-54.12.log", 12},
+		{&FileWriter{}, "app.2019-07-10T05-35-54.3.log.gz", 3},
+		{&FileWriter{HostName: true}, "app.2019-07-10T05-35-54.3.shire.log", 3},
+		{&FileWriter{ProcessID: true}, "app.2019-07-10T05-35-54.198400.log", 0},
+		{&FileWriter{ProcessID: true}, "app.2019-07-10T05-35-54.2.1234.log", 2},
+		{&FileWriter{HostName: true, ProcessID: true}, "app.2019-07-10T05-35-54.4.shire-1234.log", 4},
+		{&FileWriter{TimeFormat: TimeFormatUnix}, "app.1562736954.log", 0},
+		{&FileWriter{TimeFormat: TimeFormatUnix}, "app.1562736954.5.log", 5},
+		{&FileWriter{TimeFormat: "2006.01.02"}, "app.2019.07.10.log", 0},
+		{&FileWriter{TimeFormat: "2006.01.02"}, "app.2019.07.10.6.log", 6},
+	}
+	for _, c := range cases {
+		c.w.Filename = "/var/log/app.log"
+		if seq := c.w.backupSeq(c.name); seq != c.seq {
+			t.Errorf("backupSeq(%q) = %d, expected %d", c.name, seq, c.seq)
+		}
+		if c.seq != 0 && !c.w.ProcessID {
+			if name, _, _ := c.w.fileargs(time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC), c.seq); filepath.Base(name) != strings.TrimSuffix(c.name, ".gz") {
+				t.Errorf("fileargs sequence name %q, expected %q", name, c.name)
+			}
+		}
+	}
+}
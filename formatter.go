@@ -1,11 +1,11 @@
 package log
 
 import (
-	"reflect"
+	"math"
 	"strconv"
+	"time"
 	"unicode/utf16"
 	"unicode/utf8"
-	"unsafe"
 )
 
 // FormatterArgs is a parsed sturct from json input
@@ -52,14 +52,178 @@ func formatterArgsPos(key string) (pos int) {
 	return
 }
 
+// FieldAliases specifies the JSON keys recognised as the well-known fields of FormatterArgs,
+// so that the output of other logging libraries can be rendered.
+type FieldAliases struct {
+	Time    []string
+	Level   []string
+	Caller  []string
+	Goid    []string
+	Stack   []string
+	Message []string
+}
+
+var (
+	// ZapFieldAliases recognises the keys of zap production encoder.
+	ZapFieldAliases = &FieldAliases{
+		Time:    []string{"ts"},
+		Level:   []string{"level"},
+		Caller:  []string{"caller"},
+		Stack:   []string{"stacktrace"},
+		Message: []string{"msg"},
+	}
+
+	// ZerologFieldAliases recognises the keys of zerolog.
+	ZerologFieldAliases = &FieldAliases{
+		Time:    []string{"time"},
+		Level:   []string{"level"},
+		Caller:  []string{"caller"},
+		Stack:   []string{"stack"},
+		Message: []string{"message"},
+	}
+
+	// LogrusFieldAliases recognises the keys of logrus JSONFormatter.
+	LogrusFieldAliases = &FieldAliases{
+		Time:    []string{"time"},
+		Level:   []string{"level"},
+		Caller:  []string{"file"},
+		Message: []string{"msg"},
+	}
+
+	// GCPFieldAliases recognises the keys of Google Cloud Logging structured logs.
+	GCPFieldAliases = &FieldAliases{
+		Time:    []string{"time", "timestamp"},
+		Level:   []string{"severity"},
+		Stack:   []string{"stack_trace"},
+		Message: []string{"message"},
+	}
+
+	// ECSFieldAliases recognises the keys of Elastic Common Schema logs.
+	ECSFieldAliases = &FieldAliases{
+		Time:    []string{"@timestamp"},
+		Level:   []string{"log.level"},
+		Caller:  []string{"log.origin.file.name"},
+		Stack:   []string{"error.stack_trace"},
+		Message: []string{"message"},
+	}
+
+	// AutoFieldAliases recognises the keys of this package and all above presets.
+	AutoFieldAliases = &FieldAliases{
+		Time:    []string{"time", "ts", "timestamp", "@timestamp"},
+		Level:   []string{"level", "severity", "log.level", "lvl"},
+		Caller:  []string{"caller", "file", "log.origin.file.name"},
+		Goid:    []string{"goid"},
+		Stack:   []string{"stack", "stacktrace", "stack_trace", "error.stack_trace"},
+		Message: []string{"message", "msg"},
+	}
+)
+
+// FieldAliasesPreset returns the field aliases preset by name, e.g. "zap", "zerolog",
+// "logrus", "gcp", "ecs" or "auto". It returns nil for an unknown name.
+func FieldAliasesPreset(name string) *FieldAliases {
+	switch name {
+	case "zap":
+		return ZapFieldAliases
+	case "zerolog":
+		return ZerologFieldAliases
+	case "logrus":
+		return LogrusFieldAliases
+	case "gcp", "stackdriver":
+		return GCPFieldAliases
+	case "ecs":
+		return ECSFieldAliases
+	case "auto":
+		return AutoFieldAliases
+	}
+	return nil
+}
+
+func (aliases *FieldAliases) pos(key string) int {
+	for pos, keys := range [...][]string{
+		aliases.Time,
+		aliases.Level,
+		aliases.Caller,
+		aliases.Goid,
+		aliases.Stack,
+		aliases.Message,
+	} {
+		for _, k := range keys {
+			if k == key {
+				return pos + 1
+			}
+		}
+	}
+	return 0
+}
+
+// normalizeLevel converts the level names of other logging libraries to the names of this package.
+func normalizeLevel(s string) string {
+	switch s {
+	case "trace", "debug", "info", "warn", "error", "fatal", "panic":
+		return s
+	case "TRACE", "Trace", "TRC", "trc", "verbose", "VERBOSE":
+		return "trace"
+	case "DEBUG", "Debug", "DBG", "dbg":
+		return "debug"
+	case "INFO", "Info", "INF", "inf", "NOTICE", "notice", "DEFAULT", "default":
+		return "info"
+	case "WARN", "Warn", "WRN", "wrn", "WARNING", "Warning", "warning":
+		return "warn"
+	case "ERROR", "Error", "ERR", "err":
+		return "error"
+	case "FATAL", "Fatal", "FTL", "ftl", "CRITICAL", "critical", "crit":
+		return "fatal"
+	case "PANIC", "Panic", "PNC", "pnc", "dpanic", "DPANIC", "ALERT", "alert", "EMERGENCY", "emergency", "emerg":
+		return "panic"
+	}
+	return s
+}
+
+// formatEpoch converts a numeric epoch timestamp in seconds, milliseconds,
+// microseconds or nanoseconds to a RFC3339 time string with milliseconds.
+func formatEpoch(s string) (string, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 {
+		return s, false
+	}
+	var t time.Time
+	switch {
+	case f < 1e11:
+		sec := int64(f)
+		t = time.Unix(sec, int64(math.Round((f-float64(sec))*1e6))*1000)
+	case f < 1e14:
+		t = time.UnixMilli(int64(f))
+	case f < 1e17:
+		t = time.UnixMicro(int64(f))
+	default:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return s, false
+		}
+		t = time.Unix(0, n)
+	}
+	return t.Format("2006-01-02T15:04:05.999Z07:00"), true
+}
+
+// ParseFormatterArgs parses a JSON log entry to args, the well-known fields are
+// recognised by aliases if not nil. The values reference json, which is unescaped in place.
+func ParseFormatterArgs(json []byte, args *FormatterArgs, aliases *FieldAliases) {
+	if len(json) == 0 {
+		return
+	}
+	parseFormatterArgsWithAliases(json, args, aliases)
+}
+
 // parseFormatterArgs extracts json string to json items
 func parseFormatterArgs(json []byte, args *FormatterArgs) {
-	// treat formatter args as []string
-	const size = int(unsafe.Sizeof(FormatterArgs{}) / unsafe.Sizeof(""))
-	// nolint
-	slice := *(*[]string)(unsafe.Pointer(&reflect.SliceHeader{
-		Data: uintptr(unsafe.Pointer(args)), Len: size, Cap: size,
-	}))
+	parseFormatterArgsWithAliases(json, args, nil)
+}
+
+// parseFormatterArgsWithAliases extracts json string to json items,
+// the well-known fields are recognised by aliases if not nil.
+func parseFormatterArgsWithAliases(json []byte, args *FormatterArgs, aliases *FieldAliases) {
+	// the well-known fields by position
+	fields := [...]*string{&args.Time, &args.Level, &args.Caller, &args.Goid, &args.Stack, &args.Message}
 	var keys = true
 	var key, str []byte
 	var ok bool
@@ -96,16 +260,29 @@ func parseFormatterArgs(json []byte, args *FormatterArgs) {
 			str = jsonUnescape(str[1:len(str)-1], str[:0])
 			typ = 's'
 		}
-		pos := formatterArgsPos(b2s(key))
-		if pos == 0 && args.Time == "" {
-			pos = 1
+		var pos int
+		if aliases == nil {
+			pos = formatterArgsPos(b2s(key))
+			if pos == 0 && args.Time == "" {
+				pos = 1
+			}
+		} else {
+			pos = aliases.pos(b2s(key))
 		}
 		if pos != 0 {
 			if pos == 2 && len(str) != 0 && str[len(str)-1] == '\n' {
 				str = str[:len(str)-1]
 			}
-			if slice[pos-1] == "" {
-				slice[pos-1] = b2s(str)
+			if field := fields[pos-1]; *field == "" {
+				*field = b2s(str)
+				if aliases != nil {
+					switch {
+					case pos == 1 && typ == 'n':
+						*field, _ = formatEpoch(*field)
+					case pos == 2:
+						*field = normalizeLevel(*field)
+					}
+				}
 			}
 		} else {
 			args.KeyValues = append(args.KeyValues, struct {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"testing"
+	"time"
 )
 
 func TestFormatterParse(t *testing.T) {
@@ -20,6 +21,17 @@ func TestFormatterParse(t *testing.T) {
 	}
 }
 
+func TestParseFormatterArgs(t *testing.T) {
+	var args FormatterArgs
+	ParseFormatterArgs([]byte(`{"time":"2019-07-10T05:35:54.277Z","level":"info","caller":"main.go:42","goid":"7","msg":"hello","foo":"bar"}`), &args, nil)
+	if args.Time != "2019-07-10T05:35:54.277Z" || args.Level != "info" || args.Caller != "main.go:42" ||
+		args.Goid != "7" || args.Message != "hello" || args.Get("foo") != "bar" {
+		t.Errorf("parse formatter args got %#v", args)
+	}
+
+	ParseFormatterArgs(nil, &args, nil)
+}
+
 func TestFormatterDefault(t *testing.T) {
 	DefaultLogger.Writer = &ConsoleWriter{
 		Formatter: func(w io.Writer, a *FormatterArgs) (int, error) {
@@ -29,3 +41,72 @@ func TestFormatterDefault(t *testing.T) {
 
 	Info().Msg("aaaa 'b' cccc")
 }
+
+func TestFormatterParseAliases(t *testing.T) {
+	epoch := time.Unix(1573071825, 123000000).Format("2006-01-02T15:04:05.999Z07:00")
+
+	cases := []struct {
+		Aliases *FieldAliases
+		JSON    string
+		Time    string
+		Level   string
+		Caller  string
+		Stack   string
+		Message string
+	}{
+		{
+			ZapFieldAliases,
+			`{"level":"warn","ts":1573071825.123,"caller":"main.go:42","msg":"hello zap","foo":"bar","stacktrace":"main.main"}`,
+			epoch, "warn", "main.go:42", "main.main", "hello zap",
+		},
+		{
+			ZerologFieldAliases,
+			`{"level":"info","foo":"bar","time":"2019-07-10T05:35:54.277Z","message":"hello zerolog"}`,
+			"2019-07-10T05:35:54.277Z", "info", "", "", "hello zerolog",
+		},
+		{
+			LogrusFieldAliases,
+			`{"file":"main.go:42","level":"warning","msg":"hello logrus","time":"2019-07-10T05:35:54+08:00"}`,
+			"2019-07-10T05:35:54+08:00", "warn", "main.go:42", "", "hello logrus",
+		},
+		{
+			GCPFieldAliases,
+			`{"severity":"CRITICAL","message":"hello gcp","timestamp":"2019-07-10T05:35:54.277Z"}`,
+			"2019-07-10T05:35:54.277Z", "fatal", "", "", "hello gcp",
+		},
+		{
+			ECSFieldAliases,
+			`{"@timestamp":"2019-07-10T05:35:54.277Z","log.level":"error","message":"hello ecs","log.origin.file.name":"main.go","ecs.version":"1.6.0"}`,
+			"2019-07-10T05:35:54.277Z", "error", "main.go", "", "hello ecs",
+		},
+		{
+			AutoFieldAliases,
+			`{"level":"info","ts":1573071825123,"msg":"hello auto"}`,
+			time.UnixMilli(1573071825123).Format("2006-01-02T15:04:05.999Z07:00"), "info", "", "", "hello auto",
+		},
+		{
+			ZapFieldAliases,
+			`{"foo":"bar","msg":"no time"}`,
+			"", "????", "", "", "no time",
+		},
+	}
+
+	for _, c := range cases {
+		var args FormatterArgs
+		parseFormatterArgsWithAliases([]byte(c.JSON), &args, c.Aliases)
+		if args.Time != c.Time || args.Level != c.Level || args.Caller != c.Caller || args.Stack != c.Stack || args.Message != c.Message {
+			t.Errorf("parse %s with aliases got %#v", c.JSON, args)
+		}
+	}
+}
+
+func TestFormatterAliasesPreset(t *testing.T) {
+	for _, name := range []string{"zap", "zerolog", "logrus", "gcp", "ecs", "auto"} {
+		if FieldAliasesPreset(name) == nil {
+			t.Errorf("field aliases preset %s not found", name)
+		}
+	}
+	if FieldAliasesPreset("unknown") != nil {
+		t.Errorf("field aliases preset unknown should be nil")
+	}
+}
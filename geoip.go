@@ -0,0 +1,515 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// GeoIP enriches IP fields with geolocation and ASN sub-fields looked up from
+// local MaxMind DB (.mmdb) files, e.g. GeoLite2-City.mmdb and GeoLite2-ASN.mmdb.
+//
+// When a GeoIP is set to Logger.GeoIP, every IP logged through IPAddr or NetIPAddr
+// with key "ip" adds the following fields if found:
+//
+//	"ip.geo.country":"US","ip.geo.city":"Mountain View","ip.asn":15169
+//
+// Lookups are cached and the files are reloaded when they change on disk.
+type GeoIP struct {
+	// CityFile specifies the path of a GeoIP2/GeoLite2 City or Country database.
+	CityFile string
+
+	// ASNFile specifies the path of a GeoIP2/GeoLite2 ASN database.
+	ASNFile string
+
+	// CacheSize specifies the maximum number of cached lookups, 4096 if zero.
+	CacheSize int
+
+	// ReloadInterval specifies how often the files are checked for changes, one minute if zero.
+	ReloadInterval time.Duration
+
+	mu      sync.RWMutex
+	checked int64
+	city    *mmdb
+	asn     *mmdb
+	cache   map[netip.Addr]geoRecord
+}
+
+type geoRecord struct {
+	Country string
+	City    string
+	ASN     uint32
+}
+
+// Lookup returns the country iso code, the english city name and the autonomous system number of ip.
+func (g *GeoIP) Lookup(ip netip.Addr) (country, city string, asn uint32, err error) {
+	if err = g.reload(); err != nil {
+		return
+	}
+
+	ip = ip.Unmap()
+
+	g.mu.RLock()
+	r, ok := g.cache[ip]
+	g.mu.RUnlock()
+	if ok {
+		return r.Country, r.City, r.ASN, nil
+	}
+
+	g.mu.RLock()
+	cityDB, asnDB := g.city, g.asn
+	g.mu.RUnlock()
+
+	if cityDB != nil {
+		v, err := cityDB.lookup(ip)
+		if err != nil {
+			return "", "", 0, err
+		}
+		r.Country, _ = mmdbPath(v, "country", "iso_code").(string)
+		if r.Country == "" {
+			r.Country, _ = mmdbPath(v, "registered_country", "iso_code").(string)
+		}
+		r.City, _ = mmdbPath(v, "city", "names", "en").(string)
+	}
+	if asnDB != nil {
+		v, err := asnDB.lookup(ip)
+		if err != nil {
+			return "", "", 0, err
+		}
+		switch n := mmdbPath(v, "autonomous_system_number").(type) {
+		case uint64:
+			r.ASN = uint32(n)
+		}
+	}
+
+	size := g.CacheSize
+	if size <= 0 {
+		size = 4096
+	}
+
+	g.mu.Lock()
+	// a record of a database replaced by a concurrent reload is not cached
+	if g.city == cityDB && g.asn == asnDB {
+		if g.cache == nil {
+			g.cache = make(map[netip.Addr]geoRecord)
+		}
+		for k := range g.cache {
+			if len(g.cache) < size {
+				break
+			}
+			delete(g.cache, k)
+		}
+		g.cache[ip] = r
+	}
+	g.mu.Unlock()
+
+	return r.Country, r.City, r.ASN, nil
+}
+
+// reload (re)opens the database files if they are not loaded yet or changed on disk.
+func (g *GeoIP) reload() error {
+	interval := g.ReloadInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	checked := atomic.LoadInt64(&g.checked)
+	now := timeNow().UnixNano()
+	if checked != 0 && now-checked < int64(interval) {
+		return nil
+	}
+	if !atomic.CompareAndSwapInt64(&g.checked, checked, now) {
+		return nil
+	}
+
+	// the files are read and parsed without the lock, only the checking goroutine gets here
+	g.mu.RLock()
+	city, asn := g.city, g.asn
+	g.mu.RUnlock()
+
+	var changed bool
+	for _, f := range []struct {
+		name string
+		db   **mmdb
+	}{
+		{g.CityFile, &city},
+		{g.ASNFile, &asn},
+	} {
+		if f.name == "" {
+			continue
+		}
+		st, err := os.Stat(f.name)
+		if err != nil {
+			if *f.db == nil {
+				return err
+			}
+			continue
+		}
+		if db := *f.db; db != nil && db.modTime.Equal(st.ModTime()) && db.size == st.Size() {
+			continue
+		}
+		db, err := openMMDB(f.name)
+		if err != nil {
+			if *f.db == nil {
+				return err
+			}
+			continue
+		}
+		db.modTime, db.size = st.ModTime(), st.Size()
+		*f.db, changed = db, true
+	}
+
+	if changed {
+		g.mu.Lock()
+		g.city, g.asn = city, asn
+		g.cache = nil
+		g.mu.Unlock()
+	}
+
+	return nil
+}
+
+// enrich appends the geo sub-fields of key to the entry.
+func (g *GeoIP) enrich(e *Entry, key string, ip netip.Addr) {
+	country, city, asn, err := g.Lookup(ip)
+	if err != nil {
+		return
+	}
+	if country != "" {
+		e.buf = append(e.buf, ',', '"')
+		e.buf = append(e.buf, key...)
+		e.buf = append(e.buf, ".geo.country\":\""...)
+		e.string(country)
+		e.buf = append(e.buf, '"')
+	}
+	if city != "" {
+		e.buf = append(e.buf, ',', '"')
+		e.buf = append(e.buf, key...)
+		e.buf = append(e.buf, ".geo.city\":\""...)
+		e.string(city)
+		e.buf = append(e.buf, '"')
+	}
+	if asn != 0 {
+		e.buf = append(e.buf, ',', '"')
+		e.buf = append(e.buf, key...)
+		e.buf = append(e.buf, ".asn\":"...)
+		e.buf = strconv.AppendUint(e.buf, uint64(asn), 10)
+	}
+}
+
+// mmdbPath walks the nested maps of a decoded record.
+func mmdbPath(v interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+var errInvalidMMDB = errors.New("mmdb: invalid database")
+
+const mmdbMetadataMarker = "\xab\xcd\xefMaxMind.com"
+
+// mmdb is a minimal reader of the MaxMind DB file format.
+// See https://maxmind.github.io/MaxMind-DB/
+type mmdb struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint32
+	recordSize uint32
+	ipVersion  uint32
+	ipv4Start  uint32
+	modTime    time.Time
+	size       int64
+}
+
+func openMMDB(filename string) (*mmdb, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	start := len(buf) - 128*1024
+	if start < 0 {
+		start = 0
+	}
+	i := bytes.LastIndex(buf[start:], []byte(mmdbMetadataMarker))
+	if i < 0 {
+		return nil, errInvalidMMDB
+	}
+	meta := buf[start+i+len(mmdbMetadataMarker):]
+
+	v, _, err := mmdbDecode(meta, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &mmdb{buf: buf}
+	for _, f := range []struct {
+		key string
+		dst *uint32
+	}{
+		{"node_count", &db.nodeCount},
+		{"record_size", &db.recordSize},
+		{"ip_version", &db.ipVersion},
+	} {
+		n, ok := mmdbPath(v, f.key).(uint64)
+		if !ok {
+			return nil, errInvalidMMDB
+		}
+		*f.dst = uint32(n)
+	}
+
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, errInvalidMMDB
+	}
+
+	treeSize := int(db.recordSize) * 2 / 8 * int(db.nodeCount)
+	if treeSize+16 > start+i {
+		return nil, errInvalidMMDB
+	}
+	db.data = buf[treeSize+16 : start+i]
+
+	// ipv4 addresses live in the ::/96 subtree of an ipv6 database
+	if db.ipVersion == 6 {
+		node := uint32(0)
+		for j := 0; j < 96 && node < db.nodeCount; j++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+
+	return db, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of node.
+func (db *mmdb) record(node uint32, bit byte) uint32 {
+	switch db.recordSize {
+	case 24:
+		b := db.buf[node*6+uint32(bit)*3:]
+		return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+	case 28:
+		b := db.buf[node*7:]
+		if bit == 0 {
+			return uint32(b[3]&0xf0)<<20 | uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return uint32(b[3]&0x0f)<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+	default:
+		return binary.BigEndian.Uint32(db.buf[node*8+uint32(bit)*4:])
+	}
+}
+
+func (db *mmdb) lookup(ip netip.Addr) (interface{}, error) {
+	var node uint32
+	var bits []byte
+	if ip.Is4() {
+		a := ip.As4()
+		bits = a[:]
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else {
+		if db.ipVersion == 4 {
+			return nil, nil
+		}
+		a := ip.As16()
+		bits = a[:]
+	}
+
+	for i := 0; i < len(bits)*8 && node < db.nodeCount; i++ {
+		node = db.record(node, bits[i/8]>>(7-uint(i%8))&1)
+	}
+
+	switch {
+	case node == db.nodeCount:
+		return nil, nil
+	case node < db.nodeCount:
+		return nil, errInvalidMMDB
+	}
+
+	offset := int(node-db.nodeCount) - 16
+	if offset < 0 || offset >= len(db.data) {
+		return nil, errInvalidMMDB
+	}
+	v, _, err := mmdbDecode(db.data, offset)
+	return v, err
+}
+
+const (
+	// mmdbMaxDepth is the maximum nesting of maps, arrays and pointers in a data field.
+	mmdbMaxDepth = 32
+	// mmdbMaxFields is the maximum number of fields decoded for a data field, pointers
+	// to shared maps could otherwise expand to exponentially many within mmdbMaxDepth.
+	mmdbMaxFields = 1 << 16
+)
+
+// mmdbDecode decodes the data field at offset of the data section.
+func mmdbDecode(data []byte, offset int) (interface{}, int, error) {
+	d := mmdbDecoder{data: data}
+	return d.decode(offset, 0)
+}
+
+// mmdbDecoder counts the fields decoded for a data field.
+type mmdbDecoder struct {
+	data   []byte
+	fields int
+}
+
+// decode decodes the data field at offset, depth is its nesting level.
+func (d *mmdbDecoder) decode(offset, depth int) (interface{}, int, error) {
+	data := d.data
+	if d.fields++; offset >= len(data) || depth > mmdbMaxDepth || d.fields > mmdbMaxFields {
+		return nil, 0, errInvalidMMDB
+	}
+	ctrl := data[offset]
+	offset++
+
+	typ := int(ctrl >> 5)
+	if typ == 1 {
+		// pointer
+		ss, vvv := int(ctrl>>3)&0x3, int(ctrl&0x7)
+		if offset+ss+1 > len(data) {
+			return nil, 0, errInvalidMMDB
+		}
+		var p int
+		switch ss {
+		case 0:
+			p = vvv<<8 | int(data[offset])
+		case 1:
+			p = (vvv<<16 | int(data[offset])<<8 | int(data[offset+1])) + 2048
+		case 2:
+			p = (vvv<<24 | int(data[offset])<<16 | int(data[offset+1])<<8 | int(data[offset+2])) + 526336
+		case 3:
+			p = int(binary.BigEndian.Uint32(data[offset:]))
+		}
+		// a pointer to a pointer is invalid per spec
+		if p >= len(data) || data[p]>>5 == 1 {
+			return nil, 0, errInvalidMMDB
+		}
+		v, _, err := d.decode(p, depth+1)
+		return v, offset + ss + 1, err
+	}
+	if typ == 0 {
+		if offset >= len(data) {
+			return nil, 0, errInvalidMMDB
+		}
+		typ = 7 + int(data[offset])
+		offset++
+	}
+
+	size := int(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > len(data) {
+			return nil, 0, errInvalidMMDB
+		}
+		switch n {
+		case 1:
+			size = 29 + int(data[offset])
+		case 2:
+			size = 285 + (int(data[offset])<<8 | int(data[offset+1]))
+		case 3:
+			size = 65821 + (int(data[offset])<<16 | int(data[offset+1])<<8 | int(data[offset+2]))
+		}
+		offset += n
+	}
+
+	switch typ {
+	case 7, 11:
+		// map, array
+		var m map[string]interface{}
+		var a []interface{}
+		if typ == 7 {
+			m = make(map[string]interface{}, size)
+		} else {
+			a = make([]interface{}, 0, size)
+		}
+		for i := 0; i < size; i++ {
+			var key, value interface{}
+			var err error
+			if typ == 7 {
+				key, offset, err = d.decode(offset, depth+1)
+				if err != nil {
+					return nil, 0, err
+				}
+			}
+			value, offset, err = d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			if typ == 7 {
+				k, _ := key.(string)
+				m[k] = value
+			} else {
+				a = append(a, value)
+			}
+		}
+		if typ == 7 {
+			return m, offset, nil
+		}
+		return a, offset, nil
+	case 14:
+		// boolean
+		return size != 0, offset, nil
+	}
+
+	if offset+size > len(data) {
+		return nil, 0, errInvalidMMDB
+	}
+	b := data[offset : offset+size]
+	offset += size
+
+	switch typ {
+	case 2:
+		return string(b), offset, nil
+	case 3:
+		if size != 8 {
+			return nil, 0, errInvalidMMDB
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case 4:
+		return b, offset, nil
+	case 5, 6, 9:
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, offset, nil
+	case 8:
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int64(int32(n)), offset, nil
+	case 10:
+		// uint128 is kept as big-endian bytes
+		return b, offset, nil
+	case 15:
+		if size != 4 {
+			return nil, 0, errInvalidMMDB
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	}
+
+	return nil, 0, errInvalidMMDB
+}
+
+func netipFromIP(ip net.IP) (netip.Addr, bool) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return netip.AddrFrom4([4]byte{ip4[0], ip4[1], ip4[2], ip4[3]}), true
+	}
+	return netip.AddrFromSlice(ip)
+}
@@ -0,0 +1,331 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mmdbFixture generates a small ipv6 MaxMind DB with 24-bit records.
+func mmdbFixture(records map[string]map[string]interface{}) []byte {
+	type node struct{ rec [2]int }
+	const empty, unset = -1, -2
+
+	var data []byte
+	var enc func(v interface{})
+	ctrl := func(typ, size int) {
+		if typ > 7 {
+			data = append(data, byte(size), byte(typ-7))
+		} else {
+			data = append(data, byte(typ<<5|size))
+		}
+	}
+	enc = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			ctrl(2, len(v))
+			data = append(data, v...)
+		case uint32:
+			ctrl(6, 4)
+			data = append(data, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+		case uint16:
+			ctrl(5, 2)
+			data = append(data, byte(v>>8), byte(v))
+		case []interface{}:
+			ctrl(11, len(v))
+			for _, x := range v {
+				enc(x)
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			ctrl(7, len(v))
+			for _, k := range keys {
+				enc(k)
+				enc(v[k])
+			}
+		}
+	}
+
+	nodes := []node{{[2]int{unset, unset}}}
+	cidrs := make([]string, 0, len(records))
+	for cidr := range records {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+	for _, cidr := range cidrs {
+		pfx := netip.MustParsePrefix(cidr)
+		addr, bits := pfx.Addr().As16(), pfx.Bits()
+		if pfx.Addr().Is4() {
+			a4 := pfx.Addr().As4()
+			addr = [16]byte{12: a4[0], 13: a4[1], 14: a4[2], 15: a4[3]}
+			bits += 96
+		}
+		offset := len(data)
+		enc(records[cidr])
+		n := 0
+		for i := 0; i < bits; i++ {
+			bit := addr[i/8] >> (7 - uint(i%8)) & 1
+			if i == bits-1 {
+				nodes[n].rec[bit] = -(offset + 16) - 10
+				break
+			}
+			if nodes[n].rec[bit] < 0 {
+				nodes = append(nodes, node{[2]int{unset, unset}})
+				nodes[n].rec[bit] = len(nodes) - 1
+			}
+			n = nodes[n].rec[bit]
+		}
+	}
+
+	var buf bytes.Buffer
+	count := len(nodes)
+	for _, n := range nodes {
+		for _, r := range n.rec {
+			switch {
+			case r == unset || r == empty:
+				r = count
+			case r < 0:
+				r = count + (-r - 10)
+			}
+			buf.Write([]byte{byte(r >> 16), byte(r >> 8), byte(r)})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(data)
+
+	data = nil
+	enc(map[string]interface{}{
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(6),
+		"database_type":               "Test",
+		"languages":                   []interface{}{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+	})
+	buf.WriteString(mmdbMetadataMarker)
+	buf.Write(data)
+
+	return buf.Bytes()
+}
+
+func TestGeoIPLookup(t *testing.T) {
+	dir := t.TempDir()
+	city, asn := filepath.Join(dir, "city.mmdb"), filepath.Join(dir, "asn.mmdb")
+
+	err := os.WriteFile(city, mmdbFixture(map[string]map[string]interface{}{
+		"1.2.3.0/24": {
+			"country": map[string]interface{}{"iso_code": "AU"},
+			"city":    map[string]interface{}{"names": map[string]interface{}{"en": "Brisbane"}},
+		},
+		"2001:db8::/32": {
+			"country": map[string]interface{}{"iso_code": "JP"},
+		},
+	}), 0644)
+	if err != nil {
+		t.Fatalf("write city fixture error: %+v", err)
+	}
+	err = os.WriteFile(asn, mmdbFixture(map[string]map[string]interface{}{
+		"1.2.0.0/16": {"autonomous_system_number": uint32(13335)},
+	}), 0644)
+	if err != nil {
+		t.Fatalf("write asn fixture error: %+v", err)
+	}
+
+	g := &GeoIP{CityFile: city, ASNFile: asn}
+
+	cases := []struct {
+		IP      string
+		Country string
+		City    string
+		ASN     uint32
+	}{
+		{"1.2.3.4", "AU", "Brisbane", 13335},
+		{"1.2.4.4", "", "", 13335},
+		{"2001:db8::1", "JP", "", 0},
+		{"8.8.8.8", "", "", 0},
+	}
+
+	for _, c := range cases {
+		country, city, asn, err := g.Lookup(netip.MustParseAddr(c.IP))
+		if err != nil {
+			t.Fatalf("geoip lookup %s error: %+v", c.IP, err)
+		}
+		if country != c.Country || city != c.City || asn != c.ASN {
+			t.Errorf("geoip lookup %s want=%v got=%v,%v,%v", c.IP, c, country, city, asn)
+		}
+	}
+}
+
+func TestGeoIPLogger(t *testing.T) {
+	dir := t.TempDir()
+	city := filepath.Join(dir, "city.mmdb")
+
+	err := os.WriteFile(city, mmdbFixture(map[string]map[string]interface{}{
+		"1.2.3.0/24": {
+			"country": map[string]interface{}{"iso_code": "AU"},
+			"city":    map[string]interface{}{"names": map[string]interface{}{"en": "Brisbane"}},
+		},
+	}), 0644)
+	if err != nil {
+		t.Fatalf("write city fixture error: %+v", err)
+	}
+
+	var buf bytes.Buffer
+	logger := Logger{
+		Writer: IOWriter{&buf},
+		GeoIP:  &GeoIP{CityFile: city, ReloadInterval: time.Nanosecond},
+	}
+
+	logger.Info().IPAddr("ip", net.ParseIP("1.2.3.4")).NetIPAddr("ip6", netip.MustParseAddr("::1")).Msg("")
+	if got, want := buf.String(), `"ip":"1.2.3.4","ip.geo.country":"AU","ip.geo.city":"Brisbane","ip6":"::1"}`; !strings.Contains(got, want) {
+		t.Errorf("geoip logger want=%s got=%s", want, got)
+	}
+
+	// hot reload
+	err = os.WriteFile(city, mmdbFixture(map[string]map[string]interface{}{
+		"1.2.3.0/24": {
+			"country": map[string]interface{}{"iso_code": "NZ"},
+		},
+	}), 0644)
+	if err != nil {
+		t.Fatalf("write city fixture error: %+v", err)
+	}
+	future := time.Now().Add(time.Hour)
+	_ = os.Chtimes(city, future, future)
+
+	buf.Reset()
+	logger.Info().NetIPAddr("ip", netip.MustParseAddr("1.2.3.4")).Msg("")
+	if got, want := buf.String(), `"ip":"1.2.3.4","ip.geo.country":"NZ"}`; !strings.Contains(got, want) {
+		t.Errorf("geoip logger reload want=%s got=%s", want, got)
+	}
+}
+
+func TestGeoIPInvalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "invalid.mmdb")
+	if err := os.WriteFile(filename, []byte("not a mmdb"), 0644); err != nil {
+		t.Fatalf("write fixture error: %+v", err)
+	}
+
+	g := &GeoIP{CityFile: filename}
+	if _, _, _, err := g.Lookup(netip.MustParseAddr("1.2.3.4")); err == nil {
+		t.Errorf("geoip lookup should error")
+	}
+}
+
+func TestGeoIPMalformed(t *testing.T) {
+	// the value of "x" is replaced by a pointer to the record itself
+	fixture := mmdbFixture(map[string]map[string]interface{}{
+		"1.2.3.0/24": {"x": "PTR"},
+	})
+	fixture = bytes.Replace(fixture, []byte("\x43PTR"), []byte{0x20, 0x00, 0x20, 0x00}, 1)
+
+	filename := filepath.Join(t.TempDir(), "malformed.mmdb")
+	if err := os.WriteFile(filename, fixture, 0644); err != nil {
+		t.Fatalf("write fixture error: %+v", err)
+	}
+
+	g := &GeoIP{CityFile: filename}
+	if _, _, _, err := g.Lookup(netip.MustParseAddr("1.2.3.4")); err == nil {
+		t.Errorf("geoip lookup of a self-referencing record should error")
+	}
+
+	cases := []struct {
+		Name string
+		Data []byte
+	}{
+		{"pointer to pointer", []byte{0x20, 0x02, 0x20, 0x00}},
+		{"pointer out of range", []byte{0x20, 0x10}},
+		{"nested arrays", bytes.Repeat([]byte{0x01, 0x04}, mmdbMaxDepth+2)},
+		{"shared arrays", mmdbSharedArrays(15)},
+	}
+	for _, c := range cases {
+		if _, _, err := mmdbDecode(c.Data, 0); err != errInvalidMMDB {
+			t.Errorf("mmdb decode %s want=%v got=%v", c.Name, errInvalidMMDB, err)
+		}
+	}
+}
+
+// mmdbSharedArrays returns a data section starting with a pointer to the last of levels
+// arrays, each of 4 pointers to the previous one, which expands to 4^levels fields.
+func mmdbSharedArrays(levels int) []byte {
+	ptr := func(offset int) []byte { return []byte{0x20 | byte(offset>>8), byte(offset)} }
+	data := []byte{0, 0, 0x41, 'a'}
+	prev := 2
+	for i := 0; i < levels; i++ {
+		offset := len(data)
+		data = append(data, 0x04, 0x04)
+		for j := 0; j < 4; j++ {
+			data = append(data, ptr(prev)...)
+		}
+		prev = offset
+	}
+	copy(data, ptr(prev))
+	return data
+}
+
+func TestGeoIPCacheEviction(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "city.mmdb")
+	err := os.WriteFile(filename, mmdbFixture(map[string]map[string]interface{}{
+		"1.2.3.0/24": {"country": map[string]interface{}{"iso_code": "AU"}},
+	}), 0644)
+	if err != nil {
+		t.Fatalf("write fixture error: %+v", err)
+	}
+
+	g := &GeoIP{CityFile: filename, CacheSize: 8}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 64; j++ {
+				country, _, _, err := g.Lookup(netip.AddrFrom4([4]byte{1, 2, 3, byte(i*64 + j)}))
+				if err != nil || country != "AU" {
+					t.Errorf("geoip lookup want=AU got=%s err=%+v", country, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	g.mu.RLock()
+	n := len(g.cache)
+	g.mu.RUnlock()
+	if n == 0 || n > g.CacheSize {
+		t.Errorf("geoip cache size want<=%d got=%d", g.CacheSize, n)
+	}
+}
+
+func BenchmarkGeoIPLogger(b *testing.B) {
+	filename := filepath.Join(b.TempDir(), "city.mmdb")
+	_ = os.WriteFile(filename, mmdbFixture(map[string]map[string]interface{}{
+		"1.2.3.0/24": {"country": map[string]interface{}{"iso_code": "AU"}},
+	}), 0644)
+
+	logger := Logger{
+		Writer: IOWriter{io.Discard},
+		GeoIP:  &GeoIP{CityFile: filename},
+	}
+	ip := netip.MustParseAddr("1.2.3.4")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info().NetIPAddr("ip", ip).Msg("")
+	}
+}
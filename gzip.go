@@ -0,0 +1,110 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+// gzipFile is the destination of the gzip stream of a FileWriter, it counts the compressed bytes.
+type gzipFile struct {
+	w *FileWriter
+}
+
+func (f gzipFile) Write(p []byte) (int, error) {
+	n, err := f.w.file.Write(p)
+	f.w.zsize += int64(n)
+	return n, err
+}
+
+// openGzip starts the gzip stream of the active file if Gzip, it must be called under lock.
+func (w *FileWriter) openGzip() {
+	if !w.Gzip {
+		return
+	}
+	w.zsize = 0
+	w.gz = gzip.NewWriter(gzipFile{w})
+}
+
+// fileWrite writes to the active file, through the gzip stream if Gzip. It must be called under lock.
+func (w *FileWriter) fileWrite(p []byte) (int, error) {
+	if w.gz == nil {
+		return w.file.Write(p)
+	}
+	n, err := w.gz.Write(p)
+	if err == nil && w.flushTimer == nil {
+		interval := w.FlushInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		w.flushTimer = time.AfterFunc(interval, w.flushGzip)
+	}
+	return n, err
+}
+
+// flushGzip flushes the gzip stream of the active file, so that readers can decompress the written entries.
+func (w *FileWriter) flushGzip() {
+	w.mu.Lock()
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.flushTimer = nil
+	w.mu.Unlock()
+}
+
+// NewGzipReader returns a reader of the log lines of a gzip stream, e.g. of a FileWriter with Gzip.
+// A truncated stream, e.g. of the active file or of a crashed process, ends at the last complete line
+// which was flushed, instead of returning io.ErrUnexpectedEOF. A corrupt stream is still an error.
+func NewGzipReader(r io.Reader) (io.Reader, error) {
+	zr, err := gzip.NewReader(r)
+	switch err {
+	case nil:
+		return &gzipReader{zr: zr}, nil
+	case io.EOF, io.ErrUnexpectedEOF:
+		// the stream has no complete header yet
+		return bytes.NewReader(nil), nil
+	default:
+		return nil, err
+	}
+}
+
+type gzipReader struct {
+	zr  *gzip.Reader
+	buf []byte
+	off int
+	err error
+}
+
+func (r *gzipReader) Read(p []byte) (n int, err error) {
+	for {
+		unread := r.buf[r.off:]
+		if i := bytes.LastIndexByte(unread, '\n'); i >= 0 {
+			n = copy(p, unread[:i+1])
+			r.off += n
+			return n, nil
+		}
+		if r.err == io.ErrUnexpectedEOF {
+			// the incomplete line of a truncated stream is dropped
+			return 0, io.EOF
+		}
+		if r.err != nil {
+			if r.err == io.EOF && len(unread) != 0 {
+				n = copy(p, unread)
+				r.off += n
+				return n, nil
+			}
+			return 0, r.err
+		}
+
+		// keep the incomplete line and read more
+		r.buf = r.buf[:copy(r.buf, unread)]
+		r.off = 0
+		if len(r.buf) == cap(r.buf) {
+			r.buf = append(r.buf, make([]byte, 32*1024)...)[:len(r.buf)]
+		}
+		m, err := r.zr.Read(r.buf[len(r.buf):cap(r.buf)])
+		r.buf = r.buf[:len(r.buf)+m]
+		r.err = err
+	}
+}
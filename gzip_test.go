@@ -0,0 +1,121 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileWriterGzip(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+
+	timeNow = func() time.Time { return time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
+
+	w := &FileWriter{Filename: filename, Gzip: true, FlushInterval: 10 * time.Millisecond, Cleaner: func(string, int, []os.FileInfo) {}}
+	for i := 0; i < 3; i++ {
+		_, _ = wlprintf(w, InfoLevel, "{\"n\":%d}\n", i)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// the active stream is readable after a flush
+	active, err := os.ReadFile(filepath.Join(dir, "app.log.gz"))
+	if err != nil {
+		t.Fatalf("read active file error: %+v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(active))
+	if err != nil {
+		t.Fatalf("gzip reader error: %+v", err)
+	}
+	if _, err = io.ReadAll(zr); err != io.ErrUnexpectedEOF {
+		t.Errorf("active stream should be unterminated, got %+v", err)
+	}
+	r, err := NewGzipReader(bytes.NewReader(active))
+	if err != nil {
+		t.Fatalf("NewGzipReader error: %+v", err)
+	}
+	if data, err := io.ReadAll(r); string(data) != "{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n" || err != nil {
+		t.Errorf("active stream content not correct: %q, %+v", data, err)
+	}
+
+	// the stream ends cleanly on rotation and close
+	_ = w.Rotate()
+	_, _ = wlprintf(w, InfoLevel, "{\"n\":3}\n")
+	w.Close()
+	for name, expected := range map[string]string{
+		"app.2019-07-10T05-35-54.log.gz":   "{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n",
+		"app.2019-07-10T05-35-54.1.log.gz": "{\"n\":3}\n",
+	} {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("open %s error: %+v", name, err)
+		}
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("gzip reader of %s error: %+v", name, err)
+		}
+		if data, err := io.ReadAll(zr); string(data) != expected || err != nil {
+			t.Errorf("%s content %q not equal to %q, %+v", name, data, expected, err)
+		}
+		file.Close()
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "app.*.log")); len(matches) != 0 {
+		t.Errorf("uncompressed files should not exist: %+v", matches)
+	}
+}
+
+func TestFileWriterGzipMaxSize(t *testing.T) {
+	line := `{"level":"info","message":"the same message compresses well"}` + "\n"
+	for _, compressed := range []bool{false, true} {
+		dir := t.TempDir()
+		w := &FileWriter{
+			Filename:          filepath.Join(dir, "app.log"),
+			MaxSize:           int64(3 * len(line)),
+			Gzip:              true,
+			MaxSizeCompressed: compressed,
+			Cleaner:           func(string, int, []os.FileInfo) {},
+		}
+		for i := 0; i < 10; i++ {
+			_, _ = wlprintf(w, InfoLevel, "%s", line)
+		}
+		w.Close()
+
+		matches, _ := filepath.Glob(filepath.Join(dir, "app.*.log.gz"))
+		if expected := map[bool]int{false: 3, true: 1}[compressed]; len(matches) != expected {
+			t.Errorf("MaxSizeCompressed=%v rotated to %d files, expected %d", compressed, len(matches), expected)
+		}
+	}
+}
+
+func TestGzipReaderTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte("first line\n"))
+	_ = gz.Flush()
+	_, _ = gz.Write([]byte("second line\nthird"))
+	_ = gz.Flush()
+	_, _ = gz.Write([]byte(" line\n"))
+	gz.Close()
+
+	full := "first line\nsecond line\nthird line\n"
+	data := buf.Bytes()
+	for i := 0; i <= len(data); i++ {
+		r, err := NewGzipReader(bytes.NewReader(data[:i]))
+		if err != nil {
+			t.Fatalf("NewGzipReader of %d bytes error: %+v", i, err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("read %d bytes error: %+v", i, err)
+		}
+		if s := string(b); !strings.HasPrefix(full, s) || (s != "" && !strings.HasSuffix(s, "\n")) || (i == len(data) && s != full) {
+			t.Errorf("read %d bytes content not correct: %q", i, s)
+		}
+	}
+}
@@ -8,17 +8,59 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 )
 
+// fstat stats the descriptor that IsJournalStream compares with JOURNAL_STREAM.
+var fstat = syscall.Fstat
+
+// IsJournalStream reports whether the file descriptor is connected to the journal,
+// i.e. its device and inode numbers match the JOURNAL_STREAM environment variable set by systemd.
+func IsJournalStream(fd uintptr) bool {
+	s := getenv("JOURNAL_STREAM")
+	i := strings.IndexByte(s, ':')
+	if i <= 0 {
+		return false
+	}
+	dev, err := strconv.ParseUint(s[:i], 10, 64)
+	if err != nil {
+		return false
+	}
+	ino, err := strconv.ParseUint(s[i+1:], 10, 64)
+	if err != nil {
+		return false
+	}
+	var st syscall.Stat_t
+	if fstat(int(fd), &st) != nil {
+		return false
+	}
+	return uint64(st.Dev) == dev && uint64(st.Ino) == ino
+}
+
+func journalWriter() Writer {
+	return &JournalWriter{}
+}
+
+func init() {
+	RegisterWriterScheme("journal", func(u *url.URL) (Writer, error) {
+		return &JournalWriter{JournalSocket: u.Path}, newWriterQuery(u).done()
+	})
+}
+
 // JournalWriter is an Writer that writes logs to journald.
 type JournalWriter struct {
 	// JournalSocket specifies socket name, using `/run/systemd/journal/socket` if empty.
 	JournalSocket string
 
+	// Aliases specifies the optional keys of well-known fields, e.g. ZapFieldAliases,
+	// for forwarding the JSON output of other logging libraries.
+	Aliases *FieldAliases
+
 	once sync.Once
 	addr *net.UnixAddr
 	conn *net.UnixConn
@@ -62,7 +104,7 @@ func (w *JournalWriter) WriteEntry(e *Entry) (n int, err error) {
 	b0.B = append(b0.B, e.buf...)
 
 	var args FormatterArgs
-	parseFormatterArgs(b0.B, &args)
+	parseFormatterArgsWithAliases(b0.B, &args, w.Aliases)
 	if args.Time == "" {
 		return
 	}
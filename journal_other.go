@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package log
+
+// IsJournalStream reports whether the file descriptor is connected to the journal,
+// it is always false on non-linux systems.
+func IsJournalStream(fd uintptr) bool {
+	return false
+}
+
+func journalWriter() Writer {
+	return nil
+}
@@ -0,0 +1,322 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JournalReader reads the records of journald exported by `journalctl -o export`, or by `journalctl -o json`,
+// and converts them to JSON log entries which can be replayed through any Writer.
+//
+// PRIORITY is mapped to the level, MESSAGE to the message, CODE_FILE and CODE_LINE to the caller, and
+// _SOURCE_REALTIME_TIMESTAMP or __REALTIME_TIMESTAMP to the time. The other fields are kept as lower case keys,
+// except the address fields of the journal such as __CURSOR, and the values of a repeated field are merged into
+// an array. The records written by JournalWriter are restored from their JSON field.
+//
+//	r := log.NewJournalReader(os.Stdin)
+//	for {
+//		e, err := r.ReadEntry()
+//		if err != nil {
+//			break
+//		}
+//		w.WriteEntry(e)
+//	}
+type JournalReader struct {
+	br       *bufio.Reader
+	detected bool
+	json     bool
+	fields   []journalField
+	entry    Entry
+}
+
+type journalField struct {
+	name  string
+	value []byte
+}
+
+// journalMaxFieldSize is the maximum size of a binary field of the export format.
+const journalMaxFieldSize = 256 << 20
+
+// NewJournalReader returns a JournalReader reading from r, the format is detected from the first record.
+func NewJournalReader(r io.Reader) *JournalReader {
+	return &JournalReader{br: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// ReadEntry reads the next record and returns it as an Entry for Writer.WriteEntry, or io.EOF at the end
+// of the input. The entry is valid until the next call of ReadEntry.
+func (r *JournalReader) ReadEntry() (*Entry, error) {
+	if !r.detected {
+		for {
+			c, err := r.br.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+				_ = r.br.UnreadByte()
+				r.json = c == '{'
+				break
+			}
+		}
+		r.detected = true
+	}
+
+	var err error
+	if r.json {
+		err = r.readJSON()
+	} else {
+		err = r.readExport()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.convert(), nil
+}
+
+// readExport reads a record of the export format, the fields of NAME=value lines, or of a NAME line followed by
+// the little endian 64 bit size of a binary value, the value and a newline, terminated by an empty line.
+func (r *JournalReader) readExport() error {
+	r.fields = r.fields[:0]
+	for {
+		line, err := r.br.ReadBytes('\n')
+		if err == io.EOF && len(line) > 0 {
+			return fmt.Errorf("log: truncated journal record: %w", io.ErrUnexpectedEOF)
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+		switch i := bytes.IndexByte(line, '='); {
+		case len(line) == 0:
+			if len(r.fields) > 0 {
+				return nil
+			}
+		case i > 0:
+			r.fields = append(r.fields, journalField{string(line[:i]), line[i+1:]})
+		default:
+			var size [8]byte
+			if _, err := io.ReadFull(r.br, size[:]); err != nil {
+				return fmt.Errorf("log: truncated journal field %s: %w", line, io.ErrUnexpectedEOF)
+			}
+			n := binary.LittleEndian.Uint64(size[:])
+			if n > journalMaxFieldSize {
+				return fmt.Errorf("log: journal field %s of %d bytes is too large", line, n)
+			}
+			value := make([]byte, n+1)
+			if _, err := io.ReadFull(r.br, value); err != nil || value[n] != '\n' {
+				return fmt.Errorf("log: truncated journal field %s: %w", line, io.ErrUnexpectedEOF)
+			}
+			r.fields = append(r.fields, journalField{string(line), value[:n]})
+		}
+		if err == io.EOF {
+			if len(r.fields) > 0 {
+				return nil
+			}
+			return io.EOF
+		}
+	}
+}
+
+// readJSON reads a record of the JSON format, a JSON object per line whose values are strings, arrays of bytes
+// for binary values, arrays of the values of repeated fields, or null for the values too large to export.
+func (r *JournalReader) readJSON() error {
+	for {
+		line, err := r.br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record map[string]json.RawMessage
+			if err := json.Unmarshal(line, &record); err != nil {
+				return fmt.Errorf("log: invalid journal json record: %w", err)
+			}
+			r.fields = r.fields[:0]
+			for name, raw := range record {
+				r.fields = appendJournalJSONField(r.fields, name, raw)
+			}
+			sort.SliceStable(r.fields, func(i, j int) bool { return r.fields[i].name < r.fields[j].name })
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func appendJournalJSONField(fields []journalField, name string, raw json.RawMessage) []journalField {
+	if string(raw) == "null" {
+		return fields
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return append(fields, journalField{name, []byte(s)})
+	}
+	var b []byte
+	var values []json.RawMessage
+	if json.Unmarshal(raw, &values) != nil {
+		return fields
+	}
+	for _, v := range values {
+		var c uint8
+		if json.Unmarshal(v, &c) != nil {
+			// the values of a repeated field
+			for _, v := range values {
+				fields = appendJournalJSONField(fields, name, v)
+			}
+			return fields
+		}
+		b = append(b, c)
+	}
+	return append(fields, journalField{name, b})
+}
+
+// repeated reports whether the name of the i-th field appears in an earlier field.
+func (r *JournalReader) repeated(i int) bool {
+	for _, f := range r.fields[:i] {
+		if f.name == r.fields[i].name {
+			return true
+		}
+	}
+	return false
+}
+
+// count returns the number of the fields from the i-th one having its name.
+func (r *JournalReader) count(i int) (n int) {
+	for _, f := range r.fields[i:] {
+		if f.name == r.fields[i].name {
+			n++
+		}
+	}
+	return
+}
+
+func (r *JournalReader) field(name string) []byte {
+	for _, f := range r.fields {
+		if f.name == name {
+			return f.value
+		}
+	}
+	return nil
+}
+
+func (r *JournalReader) convert() *Entry {
+	e := &r.entry
+	e.buf = e.buf[:0]
+	e.Level = noLevel
+	e.Message = ""
+
+	// the records of JournalWriter
+	if v := bytes.TrimSpace(r.field("JSON")); len(v) > 0 {
+		if members, ok := jsonMembers(v); ok {
+			for _, m := range members {
+				switch m.key {
+				case "level":
+					e.Level = ParseLevel(m.value(v))
+				case "message", "msg":
+					e.Message = m.value(v)
+				}
+			}
+			e.buf = append(e.buf, v...)
+			e.buf = append(e.buf, '\n')
+			return e
+		}
+	}
+
+	e.buf = append(e.buf, '{')
+
+	// time
+	timeField := "_SOURCE_REALTIME_TIMESTAMP"
+	usec, err := strconv.ParseInt(string(r.field(timeField)), 10, 64)
+	if err != nil {
+		timeField = "__REALTIME_TIMESTAMP"
+		usec, err = strconv.ParseInt(string(r.field(timeField)), 10, 64)
+	}
+	if err == nil {
+		e.buf = append(e.buf, ",\"time\":\""...)
+		e.buf = time.Unix(usec/1e6, usec%1e6*1e3).UTC().AppendFormat(e.buf, "2006-01-02T15:04:05.000Z07:00")
+		e.buf = append(e.buf, '"')
+	}
+
+	// level
+	switch string(r.field("PRIORITY")) {
+	case "0":
+		e.Level = PanicLevel
+	case "1", "2":
+		e.Level = FatalLevel
+	case "3":
+		e.Level = ErrorLevel
+	case "4":
+		e.Level = WarnLevel
+	case "5", "6":
+		e.Level = InfoLevel
+	case "7":
+		e.Level = DebugLevel
+	}
+	if e.Level != noLevel {
+		e.buf = append(e.buf, ",\"level\":\""...)
+		e.buf = append(e.buf, e.Level.String()...)
+		e.buf = append(e.buf, '"')
+	}
+
+	// caller
+	if file := r.field("CODE_FILE"); len(file) > 0 {
+		e.buf = append(e.buf, ",\"caller\":\""...)
+		e.bytes(file)
+		if line := r.field("CODE_LINE"); len(line) > 0 {
+			e.buf = append(e.buf, ':')
+			e.bytes(line)
+		}
+		e.buf = append(e.buf, '"')
+	}
+
+	// fields
+	for i, f := range r.fields {
+		switch {
+		case strings.HasPrefix(f.name, "__"), f.name == timeField:
+			continue
+		case f.name == "PRIORITY", f.name == "MESSAGE", f.name == "CODE_FILE", f.name == "CODE_LINE":
+			continue
+		}
+		if r.repeated(i) {
+			continue
+		}
+		e.buf = append(e.buf, ',', '"')
+		e.string(strings.ToLower(f.name))
+		e.buf = append(e.buf, '"', ':')
+		if n := r.count(i); n > 1 {
+			// the values of a repeated field
+			e.buf = append(e.buf, '[')
+			for _, g := range r.fields[i:] {
+				if g.name == f.name {
+					e.buf = append(e.buf, '"')
+					e.bytes(g.value)
+					e.buf = append(e.buf, '"', ',')
+				}
+			}
+			e.buf[len(e.buf)-1] = ']'
+		} else {
+			e.buf = append(e.buf, '"')
+			e.bytes(f.value)
+			e.buf = append(e.buf, '"')
+		}
+	}
+
+	// message
+	if msg := r.field("MESSAGE"); msg != nil {
+		e.Message = string(msg)
+		e.buf = append(e.buf, ",\"message\":\""...)
+		e.bytes(msg)
+		e.buf = append(e.buf, '"')
+	}
+
+	e.buf = append(e.buf, '}', '\n')
+	if len(e.buf) > 3 {
+		// the comma before the first field
+		e.buf = append(e.buf[:1], e.buf[2:]...)
+	}
+	return e
+}
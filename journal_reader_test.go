@@ -0,0 +1,97 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readJournalFixture(t *testing.T, name string) []string {
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("open journal fixture error: %+v", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	r := NewJournalReader(file)
+	for {
+		e, err := r.ReadEntry()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read journal entry error: %+v", err)
+		}
+		lines = append(lines, string(e.buf))
+	}
+	return lines
+}
+
+func TestJournalReaderExport(t *testing.T) {
+	lines := readJournalFixture(t, "testdata/journal.export")
+	want := []string{
+		`{"time":"2019-07-10T05:35:54.277Z","level":"warn","caller":"charge.go:88","_boot_id":"6c7c6013a8b843d2b5b8a9e3d7e0f2a1","_pid":"1423","_comm":"billing","_systemd_unit":"billing.service","code_func":"main.charge","_hostname":"web-1","message":"charge failed\ncard declined"}` + "\n",
+		`{"time":"2019-07-10T05:35:54.999Z","level":"info","caller":"main.go:42","foo":"bar","message":"hello journal writer"}` + "\n",
+		`{"time":"2019-07-10T05:35:56.500Z","_transport":"kernel","message":"eth0: link up"}` + "\n",
+	}
+	if strings.Join(lines, "") != strings.Join(want, "") {
+		t.Errorf("journal export entries not correct:\n%s", strings.Join(lines, ""))
+	}
+}
+
+func TestJournalReaderJSON(t *testing.T) {
+	lines := readJournalFixture(t, "testdata/journal.json")
+	want := []string{
+		`{"time":"2019-07-10T05:35:54.277Z","level":"warn","caller":"charge.go:88","code_func":"main.charge","_boot_id":"6c7c6013a8b843d2b5b8a9e3d7e0f2a1","_comm":"billing","_hostname":"web-1","_pid":"1423","_systemd_unit":"billing.service","message":"charge failed\ncard declined"}` + "\n",
+		`{"time":"2019-07-10T05:35:56.500Z","tag":["a","b"],"_transport":"kernel","message":"eth0: link up"}` + "\n",
+	}
+	if strings.Join(lines, "") != strings.Join(want, "") {
+		t.Errorf("journal json entries not correct:\n%s", strings.Join(lines, ""))
+	}
+}
+
+func TestJournalReaderRepeated(t *testing.T) {
+	data := "__REALTIME_TIMESTAMP=1562736956500000\nTAG=a\nMESSAGE=eth0: link up\nTAG=b\nUNIT=net\nTAG=c\n\n"
+	r := NewJournalReader(strings.NewReader(data))
+	e, err := r.ReadEntry()
+	if err != nil {
+		t.Fatalf("read journal entry error: %+v", err)
+	}
+	want := `{"time":"2019-07-10T05:35:56.500Z","tag":["a","b","c"],"unit":"net","message":"eth0: link up"}` + "\n"
+	if string(e.buf) != want {
+		t.Errorf("journal repeated fields not correct: %s", e.buf)
+	}
+}
+
+func TestJournalReaderReplay(t *testing.T) {
+	data, err := os.ReadFile("testdata/journal.export")
+	if err != nil {
+		t.Fatalf("read journal fixture error: %+v", err)
+	}
+
+	var buf bytes.Buffer
+	w := &ConsoleWriter{Writer: &buf}
+	r := NewJournalReader(bytes.NewReader(data))
+	for {
+		e, err := r.ReadEntry()
+		if err != nil {
+			break
+		}
+		if _, err := w.WriteEntry(e); err != nil {
+			t.Fatalf("replay journal entry error: %+v", err)
+		}
+	}
+	if !strings.HasPrefix(buf.String(), "2019-07-10T05:35:54.277Z WRN  charge.go:88 > charge failed\\ncard declined _boot_id=") {
+		t.Errorf("replay journal entries not correct:\n%s", buf.String())
+	}
+
+	for _, n := range []int{bytes.Index(data, []byte("MESSAGE\n")) + 12, bytes.Index(data, []byte("_HOSTNAME=")) + 4} {
+		r = NewJournalReader(bytes.NewReader(data[:n]))
+		if _, err := r.ReadEntry(); err == nil || err == io.EOF {
+			t.Errorf("read truncated journal should return an error: %+v", err)
+		}
+	}
+}
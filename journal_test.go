@@ -6,6 +6,7 @@ package log
 import (
 	"net"
 	"os"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -59,3 +60,79 @@ func TestJournalWriterError(t *testing.T) {
 	_, _ = wlprintf(w, InfoLevel, "a long long long long message.\n")
 	w.Close()
 }
+
+func TestIsJournalStream(t *testing.T) {
+	defer func() {
+		getenv, fstat = os.Getenv, syscall.Fstat
+	}()
+	fstat = func(fd int, st *syscall.Stat_t) error {
+		if fd != 2 {
+			return syscall.EBADF
+		}
+		st.Dev, st.Ino = 27, 1234
+		return nil
+	}
+
+	cases := []struct {
+		Env  string
+		FD   uintptr
+		Want bool
+	}{
+		{"27:1234", 2, true},
+		{"27:1235", 2, false},
+		{"28:1234", 2, false},
+		{"27:1234", 1, false},
+		{"", 2, false},
+		{"27", 2, false},
+		{"a:b", 2, false},
+	}
+
+	for _, c := range cases {
+		getenv = func(key string) string {
+			if key == "JOURNAL_STREAM" {
+				return c.Env
+			}
+			return ""
+		}
+		if got := IsJournalStream(c.FD); got != c.Want {
+			t.Errorf("IsJournalStream(%d) with JOURNAL_STREAM=%q want=%v got=%v", c.FD, c.Env, c.Want, got)
+		}
+	}
+}
+
+func TestAutoWriter(t *testing.T) {
+	defer func(w Writer) {
+		getenv, fstat = os.Getenv, syscall.Fstat
+		DefaultLogger.Writer = w
+	}(DefaultLogger.Writer)
+
+	fstat = func(fd int, st *syscall.Stat_t) error {
+		st.Dev, st.Ino = 27, 1234
+		return nil
+	}
+	getenv = func(key string) string { return "27:1234" }
+
+	UseAutoWriter(nil)
+	if _, ok := DefaultLogger.Writer.(*JournalWriter); !ok {
+		t.Errorf("auto writer should be journal writer, got %T", DefaultLogger.Writer)
+	}
+
+	getenv = func(key string) string { return "" }
+	var logger Logger
+	UseAutoWriter(&logger)
+	switch logger.Writer.(type) {
+	case *ConsoleWriter, IOWriter:
+	default:
+		t.Errorf("auto writer should fallback to console or stderr, got %T", logger.Writer)
+	}
+}
+
+func TestOpenJournalWriter(t *testing.T) {
+	w, err := OpenWriter("journal:///run/systemd/journal/socket")
+	if err != nil {
+		t.Fatalf("open journal writer error: %+v", err)
+	}
+	if jw, ok := w.(*JournalWriter); !ok || jw.JournalSocket != "/run/systemd/journal/socket" {
+		t.Errorf("open journal writer got %#v", w)
+	}
+}
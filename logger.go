@@ -37,6 +37,8 @@ type Entry struct {
 	Dt      string                   `json:"dt"`
 	Message string                   `json:"message"`
 	Data    []map[string]interface{} `json:"-"`
+	geoip   *GeoIP
+	budget  *Budget
 }
 
 // Writer defines an entry writer interface.
@@ -102,6 +104,18 @@ type Logger struct {
 
 	// GoSync specifies if the call to BetterStack should run in routine
 	GoSync bool
+
+	// GeoIP specifies an optional enrichment of IP fields with geolocation and ASN sub-fields.
+	GeoIP *GeoIP
+
+	// Sampler specifies an optional sampler deciding by SampleKey which entries are kept.
+	Sampler *HashSampler
+
+	// SampleKey specifies the sampling key of the logger, e.g. a trace id. Entries are not sampled if empty.
+	SampleKey string
+
+	// Budget specifies an optional volume budget shared by the loggers derived from the logger.
+	Budget *Budget
 }
 
 // TimeFormatUnix defines a time format that makes time fields to be
@@ -116,38 +130,6 @@ const TimeFormatUnixMs = "\x02"
 // serialized as Unix timestamp timestamp floats.
 const TimeFormatUnixWithMs = "\x03"
 
-// Trace starts a new message with trace level.
-func Trace() (e *Entry) {
-	if DefaultLogger.silent(TraceLevel) {
-		return nil
-	}
-	e = DefaultLogger.header(TraceLevel)
-	if caller, full := DefaultLogger.Caller, false; caller != 0 {
-		if caller < 0 {
-			caller, full = -caller, true
-		}
-		var rpc [1]uintptr
-		e.caller(callers(caller, rpc[:]), rpc[:], full)
-	}
-	return
-}
-
-// Debug starts a new message with debug level.
-func Debug() (e *Entry) {
-	if DefaultLogger.silent(DebugLevel) {
-		return nil
-	}
-	e = DefaultLogger.header(DebugLevel)
-	if caller, full := DefaultLogger.Caller, false; caller != 0 {
-		if caller < 0 {
-			caller, full = -caller, true
-		}
-		var rpc [1]uintptr
-		e.caller(callers(caller, rpc[:]), rpc[:], full)
-	}
-	return
-}
-
 // Info starts a new message with info level.
 func Info() (e *Entry) {
 	if DefaultLogger.silent(InfoLevel) {
@@ -247,38 +229,6 @@ func (l *Logger) SetToken(token string) {
 	l.BetterStackToken = token
 }
 
-// Trace starts a new message with trace level.
-func (l *Logger) Trace() (e *Entry) {
-	if l.silent(TraceLevel) {
-		return nil
-	}
-	e = l.header(TraceLevel)
-	if caller, full := l.Caller, false; caller != 0 {
-		if caller < 0 {
-			caller, full = -caller, true
-		}
-		var rpc [1]uintptr
-		e.caller(callers(caller, rpc[:]), rpc[:], full)
-	}
-	return
-}
-
-// Debug starts a new message with debug level.
-func (l *Logger) Debug() (e *Entry) {
-	if l.silent(DebugLevel) {
-		return nil
-	}
-	e = l.header(DebugLevel)
-	if caller, full := l.Caller, false; caller != 0 {
-		if caller < 0 {
-			caller, full = -caller, true
-		}
-		var rpc [1]uintptr
-		e.caller(callers(caller, rpc[:]), rpc[:], full)
-	}
-	return
-}
-
 // Info starts a new message with info level.
 func (l *Logger) Info() (e *Entry) {
 	if l.silent(InfoLevel) {
@@ -464,11 +414,30 @@ var timeOffset, timeZone = func() (int64, string) {
 }()
 
 func (l *Logger) silent(level Level) bool {
-	return uint32(level) < atomic.LoadUint32((*uint32)(&l.Level))
+	if uint32(level) < atomic.LoadUint32((*uint32)(&l.Level)) {
+		return true
+	}
+	return (l.Sampler != nil || l.Budget != nil) && l.dropped(level)
+}
+
+// dropped reports whether an entry of an enabled level is dropped by the sampler or the budget,
+// it is kept out of silent so that the level check of disabled logging inlines.
+//
+//go:noinline
+func (l *Logger) dropped(level Level) bool {
+	if l.Sampler != nil && !l.Sampler.Sample(level, l.SampleKey) {
+		return true
+	}
+	if l.Budget != nil {
+		ok, now := l.Budget.allow(level)
+		l.Budget.summarize(l, now)
+		return !ok
+	}
+	return false
 }
 
 func (l *Logger) header(level Level) *Entry {
-	e := epool.Get().(*Entry)
+	e := getEntry()
 	e.buf = e.buf[:0]
 	e.Level = level
 	if l.Writer != nil {
@@ -476,6 +445,8 @@ func (l *Logger) header(level Level) *Entry {
 	} else {
 		e.w = IOWriter{os.Stderr}
 	}
+	e.geoip = l.GeoIP
+	e.budget = l.Budget
 	// time
 	if l.TimeField == "" {
 		e.buf = append(e.buf, "{\"time\":"...)
@@ -680,6 +651,7 @@ func (e *Entry) Time(key string, t time.Time) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -693,6 +665,7 @@ func (e *Entry) TimeFormat(key string, timefmt string, t time.Time) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -718,6 +691,7 @@ func (e *Entry) Times(key string, a []time.Time) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -739,6 +713,7 @@ func (e *Entry) TimesFormat(key string, timefmt string, a []time.Time) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -771,6 +746,7 @@ func (e *Entry) Bool(key string, b bool) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -783,6 +759,7 @@ func (e *Entry) Bools(key string, b []bool) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -801,6 +778,7 @@ func (e *Entry) Dur(key string, d time.Duration) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -835,6 +813,7 @@ func (e *Entry) TimeDiff(key string, t time.Time, start time.Time) *Entry {
 	if e == nil {
 		return e
 	}
+	checkEntry(e)
 	var d time.Duration
 	if t.After(start) {
 		d = t.Sub(start)
@@ -867,6 +846,7 @@ func (e *Entry) Durs(key string, d []time.Duration) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -910,6 +890,7 @@ func (e *Entry) AnErr(key string, err error) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 
 	if err == nil {
 		e.buf = append(e.buf, ',', '"')
@@ -936,6 +917,7 @@ func (e *Entry) Errs(key string, errs []error) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
@@ -961,6 +943,7 @@ func (e *Entry) Float64(key string, f float64) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -973,6 +956,7 @@ func (e *Entry) Floats64(key string, f []float64) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -991,6 +975,7 @@ func (e *Entry) Floats32(key string, f []float32) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1009,6 +994,7 @@ func (e *Entry) Int64(key string, i int64) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -1021,6 +1007,7 @@ func (e *Entry) Uint(key string, i uint) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -1033,6 +1020,7 @@ func (e *Entry) Uint64(key string, i uint64) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -1085,6 +1073,7 @@ func (e *Entry) Ints64(key string, a []int64) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1103,6 +1092,7 @@ func (e *Entry) Ints32(key string, a []int32) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1121,6 +1111,7 @@ func (e *Entry) Ints16(key string, a []int16) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1139,6 +1130,7 @@ func (e *Entry) Ints8(key string, a []int8) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1157,6 +1149,7 @@ func (e *Entry) Ints(key string, a []int) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1175,6 +1168,7 @@ func (e *Entry) Uints64(key string, a []uint64) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1193,6 +1187,7 @@ func (e *Entry) Uints32(key string, a []uint32) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1211,6 +1206,7 @@ func (e *Entry) Uints16(key string, a []uint16) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1229,6 +1225,7 @@ func (e *Entry) Uints8(key string, a []uint8) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1247,6 +1244,7 @@ func (e *Entry) Uints(key string, a []uint) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1265,6 +1263,7 @@ func (e *Entry) RawJSON(key string, b []byte) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -1277,6 +1276,7 @@ func (e *Entry) RawJSONStr(key string, s string) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -1289,6 +1289,7 @@ func (e *Entry) Str(key string, val string) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1303,6 +1304,7 @@ func (e *Entry) StrInt(key string, val int64) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1316,6 +1318,7 @@ func (e *Entry) Stringer(key string, val fmt.Stringer) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -1334,6 +1337,7 @@ func (e *Entry) GoStringer(key string, val fmt.GoStringer) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -1352,6 +1356,7 @@ func (e *Entry) Strs(key string, vals []string) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '[')
@@ -1372,6 +1377,7 @@ func (e *Entry) Byte(key string, val byte) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -1407,6 +1413,7 @@ func (e *Entry) Bytes(key string, val []byte) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1420,6 +1427,7 @@ func (e *Entry) BytesOrNil(key string, val []byte) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':')
@@ -1440,6 +1448,7 @@ func (e *Entry) Hex(key string, val []byte) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1455,6 +1464,7 @@ func (e *Entry) Xid(key string, xid [12]byte) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1469,6 +1479,7 @@ func (e *Entry) IPAddr(key string, ip net.IP) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1484,6 +1495,11 @@ func (e *Entry) IPAddr(key string, ip net.IP) *Entry {
 		e.buf = append(e.buf, ip.String()...)
 	}
 	e.buf = append(e.buf, '"')
+	if e.geoip != nil {
+		if addr, ok := netipFromIP(ip); ok {
+			e.geoip.enrich(e, key, addr)
+		}
+	}
 	return e
 }
 
@@ -1492,6 +1508,7 @@ func (e *Entry) IPPrefix(key string, pfx net.IPNet) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1505,6 +1522,7 @@ func (e *Entry) MACAddr(key string, ha net.HardwareAddr) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1524,11 +1542,15 @@ func (e *Entry) NetIPAddr(key string, ip netip.Addr) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
 	e.buf = ip.AppendTo(e.buf)
 	e.buf = append(e.buf, '"')
+	if e.geoip != nil && ip.IsValid() {
+		e.geoip.enrich(e, key, ip)
+	}
 	return e
 }
 
@@ -1537,6 +1559,7 @@ func (e *Entry) NetIPAddrPort(key string, ipPort netip.AddrPort) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1550,6 +1573,7 @@ func (e *Entry) NetIPPrefix(key string, pfx netip.Prefix) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1563,6 +1587,7 @@ func (e *Entry) Type(key string, v interface{}) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '"')
@@ -1575,6 +1600,7 @@ func (e *Entry) Type(key string, v interface{}) *Entry {
 // If depth is negative, adds the full /path/to/file:line of the "caller" key.
 func (e *Entry) Caller(depth int) *Entry {
 	if e != nil {
+		checkEntry(e)
 		var full bool
 		var rpc [1]uintptr
 		if depth < 0 {
@@ -1588,6 +1614,7 @@ func (e *Entry) Caller(depth int) *Entry {
 // Stack enables stack trace printing for the error passed to Err().
 func (e *Entry) Stack() *Entry {
 	if e != nil {
+		checkEntry(e)
 		e.buf = append(e.buf, ",\"stack\":\""...)
 		e.bytes(stacks(false))
 		e.buf = append(e.buf, '"')
@@ -1605,9 +1632,8 @@ func (e *Entry) Discard() *Entry {
 	if e == nil {
 		return e
 	}
-	if cap(e.buf) <= bbcap {
-		epool.Put(e)
-	}
+	checkEntry(e)
+	putEntry(e)
 	return nil
 }
 
@@ -1618,6 +1644,7 @@ func (e *Entry) Msg(msg string) {
 	if e == nil {
 		return
 	}
+	checkEntry(e)
 	if msg != "" {
 		e.buf = append(e.buf, ",\"message\":\""...)
 		e.string(msg)
@@ -1626,16 +1653,18 @@ func (e *Entry) Msg(msg string) {
 		e.buf = append(e.buf, '}', '\n')
 	}
 	e.Message = msg
+	if e.budget != nil {
+		e.budget.charge(len(e.buf))
+	}
 	_, _ = e.w.WriteEntry(e)
-	if (e.Level == FatalLevel) && notTest {
+	level := e.Level
+	putEntry(e)
+	if (level == FatalLevel) && notTest {
 		os.Exit(255)
 	}
-	if (e.Level == PanicLevel) && notTest {
+	if (level == PanicLevel) && notTest {
 		panic(msg)
 	}
-	if cap(e.buf) <= bbcap {
-		epool.Put(e)
-	}
 
 	if DefaultLogger.BetterStackToken != "" {
 		if DefaultLogger.GoSync {
@@ -1674,6 +1703,7 @@ func (e *Entry) Msgf(format string, v ...interface{}) {
 	if e == nil {
 		return
 	}
+	checkEntry(e)
 	b := bbpool.Get().(*bb)
 	b.B = b.B[:0]
 	e.buf = append(e.buf, ",\"message\":\""...)
@@ -1706,6 +1736,7 @@ func (e *Entry) Msgs(args ...interface{}) {
 	if e == nil {
 		return
 	}
+	checkEntry(e)
 	b := bbpool.Get().(*bb)
 	b.B = b.B[:0]
 	e.buf = append(e.buf, ",\"message\":\""...)
@@ -1889,6 +1920,7 @@ func (e *Entry) Interface(key string, i interface{}) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 
 	if o, ok := i.(ObjectMarshaler); ok {
 		return e.Object(key, o)
@@ -1922,6 +1954,7 @@ func (e *Entry) Object(key string, obj ObjectMarshaler) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
@@ -1946,6 +1979,7 @@ func (e *Entry) Object(key string, obj ObjectMarshaler) *Entry {
 // Func allows an anonymous func to run only if the entry is enabled.
 func (e *Entry) Func(f func(e *Entry)) *Entry {
 	if e != nil {
+		checkEntry(e)
 		f(e)
 	}
 	return e
@@ -1956,6 +1990,7 @@ func (e *Entry) EmbedObject(obj ObjectMarshaler) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 
 	if obj != nil && (*[2]uintptr)(unsafe.Pointer(&obj))[1] != 0 {
 		obj.MarshalObject(e)
@@ -2052,6 +2087,7 @@ func (e *Entry) KeysAndValues(keysAndValues ...interface{}) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	var key string
 	for i, v := range keysAndValues {
 		if i%2 == 0 {
@@ -2071,6 +2107,7 @@ func (e *Entry) Fields(fields Fields) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	for key, value := range fields {
 		e.Any(key, value)
 	}
@@ -2092,6 +2129,7 @@ func (e *Entry) Value() Context {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	return e.buf
 }
 
@@ -2100,6 +2138,7 @@ func (e *Entry) Context(ctx Context) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	if len(ctx) != 0 {
 		e.buf = append(e.buf, ctx...)
 	}
@@ -2136,6 +2175,7 @@ func (e *Entry) Dict(key string, ctx Context) *Entry {
 	if e == nil {
 		return nil
 	}
+	checkEntry(e)
 	e.buf = append(e.buf, ',', '"')
 	e.buf = append(e.buf, key...)
 	e.buf = append(e.buf, '"', ':', '{')
@@ -2191,6 +2231,12 @@ func absClock(abs uint64) (hour, min, sec int)
 //go:linkname callers runtime.callers
 func callers(skip int, pcbuf []uintptr) int
 
+//go:linkname procPin runtime.procPin
+func procPin() int
+
+//go:linkname procUnpin runtime.procUnpin
+func procUnpin()
+
 // Fastrandn returns a pseudorandom uint32 in [0,n).
 //
 //go:noescape
@@ -0,0 +1,36 @@
+//go:build !logstack_nodebug
+// +build !logstack_nodebug
+
+package log
+
+// Debug starts a new message with debug level.
+func Debug() (e *Entry) {
+	if DefaultLogger.silent(DebugLevel) {
+		return nil
+	}
+	e = DefaultLogger.header(DebugLevel)
+	if caller, full := DefaultLogger.Caller, false; caller != 0 {
+		if caller < 0 {
+			caller, full = -caller, true
+		}
+		var rpc [1]uintptr
+		e.caller(callers(caller, rpc[:]), rpc[:], full)
+	}
+	return
+}
+
+// Debug starts a new message with debug level.
+func (l *Logger) Debug() (e *Entry) {
+	if l.silent(DebugLevel) {
+		return nil
+	}
+	e = l.header(DebugLevel)
+	if caller, full := l.Caller, false; caller != 0 {
+		if caller < 0 {
+			caller, full = -caller, true
+		}
+		var rpc [1]uintptr
+		e.caller(callers(caller, rpc[:]), rpc[:], full)
+	}
+	return
+}
@@ -0,0 +1,15 @@
+//go:build logstack_nodebug
+// +build logstack_nodebug
+
+package log
+
+// Debug returns nil, the level check and encoding of debug logging are eliminated by the logstack_nodebug build tag.
+// WithLevel(DebugLevel) is not eliminated, nor are the compat loggers built on it.
+func Debug() (e *Entry) {
+	return nil
+}
+
+// Debug returns nil, the level check and encoding of debug logging are eliminated by the logstack_nodebug build tag.
+func (l *Logger) Debug() (e *Entry) {
+	return nil
+}
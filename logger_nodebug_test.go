@@ -0,0 +1,43 @@
+//go:build logstack_nodebug
+// +build logstack_nodebug
+
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLoggerNoDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		Level:  TraceLevel,
+		Writer: IOWriter{&buf},
+	}
+
+	if e := logger.Debug(); e != nil {
+		t.Errorf("logger debug should be nil under logstack_nodebug")
+	}
+	logger.Trace().Str("foo", "bar").Msg("hello from Trace")
+	logger.Debug().Str("foo", "bar").Msg("hello from Debug")
+	logger.Info().Str("foo", "bar").Msg("hello from Info")
+
+	if got := buf.String(); got == "" || strings.Contains(got, "hello from Debug") || strings.Contains(got, "hello from Trace") {
+		t.Errorf("logger nodebug output not correct: %s", got)
+	}
+
+	// WithLevel is a runtime level check, it is not eliminated
+	buf.Reset()
+	logger.WithLevel(DebugLevel).Msg("hello from WithLevel")
+	if got := buf.String(); !strings.Contains(got, "hello from WithLevel") {
+		t.Errorf("logger nodebug should keep WithLevel(DebugLevel): %s", got)
+	}
+
+	level := DefaultLogger.Level
+	DefaultLogger.SetLevel(TraceLevel)
+	defer DefaultLogger.SetLevel(level)
+	if e := Debug(); e != nil {
+		t.Errorf("package debug should be nil under logstack_nodebug")
+	}
+}
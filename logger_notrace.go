@@ -0,0 +1,14 @@
+//go:build logstack_notrace || logstack_nodebug
+// +build logstack_notrace logstack_nodebug
+
+package log
+
+// Trace returns nil, the level check and encoding of trace logging are eliminated by the logstack_notrace or logstack_nodebug build tag.
+func Trace() (e *Entry) {
+	return nil
+}
+
+// Trace returns nil, the level check and encoding of trace logging are eliminated by the logstack_notrace or logstack_nodebug build tag.
+func (l *Logger) Trace() (e *Entry) {
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build logstack_notrace || logstack_nodebug
+// +build logstack_notrace logstack_nodebug
+
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLoggerNoTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		Level:  TraceLevel,
+		Writer: IOWriter{&buf},
+	}
+
+	if e := logger.Trace(); e != nil {
+		t.Errorf("logger trace should be nil under logstack_notrace")
+	}
+	logger.Trace().Str("foo", "bar").Msg("hello from Trace")
+	logger.Info().Str("foo", "bar").Msg("hello from Info")
+
+	if got := buf.String(); strings.Contains(got, "hello from Trace") || !strings.Contains(got, "hello from Info") {
+		t.Errorf("logger notrace output not correct: %s", got)
+	}
+
+	level := DefaultLogger.Level
+	DefaultLogger.SetLevel(TraceLevel)
+	defer DefaultLogger.SetLevel(level)
+	if e := Trace(); e != nil {
+		t.Errorf("package trace should be nil under logstack_notrace")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	stdLog "log"
 	"net"
 	"os"
+	"os/exec"
 	"strings"
 	"testing"
 	"time"
@@ -657,3 +658,79 @@ func setBSToken(l *Logger) string {
 
 	return ""
 }
+
+func TestLoggerEliminatedChains(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiler check in short mode")
+	}
+
+	const pkg = "github.com/fabricatorsltd/logstack."
+	trace := []string{pkg + "Trace", pkg + "(*Logger).Trace"}
+	debug := []string{pkg + "Debug", pkg + "(*Logger).Debug"}
+	cases := []struct {
+		Tags    string
+		Calls   []string
+		NoCalls []string
+	}{
+		{"", append(trace, debug...), nil},
+		{"logstack_notrace", debug, trace},
+		{"logstack_nodebug", nil, append(trace, debug...)},
+	}
+
+	for _, c := range cases {
+		// testdata/disabled chains fields and messages on Trace and Debug of the package and the Logger.
+		out, err := exec.Command("go", "build", "-gcflags=-S", "-tags="+c.Tags, "-o", os.DevNull, "./testdata/disabled").CombinedOutput()
+		if err != nil {
+			t.Fatalf("go build -tags=%q error: %+v: %s", c.Tags, err, out)
+		}
+
+		calls := make(map[string]bool)
+		inMain := false
+		for _, line := range strings.Split(string(out), "\n") {
+			switch {
+			case strings.HasPrefix(line, "main.main STEXT"):
+				inMain = true
+			case strings.Contains(line, " STEXT"):
+				inMain = false
+			case inMain && strings.Contains(line, "\tCALL\t"):
+				call := line[strings.Index(line, "\tCALL\t")+len("\tCALL\t"):]
+				calls[strings.TrimSuffix(call, "(SB)")] = true
+			}
+		}
+
+		for _, call := range c.Calls {
+			if !calls[call] {
+				t.Errorf("go build -tags=%q: %s is not called: %v", c.Tags, call, calls)
+			}
+		}
+		for _, call := range c.NoCalls {
+			if calls[call] {
+				t.Errorf("go build -tags=%q: %s is not eliminated", c.Tags, call)
+			}
+		}
+		if c.Tags != "logstack_nodebug" {
+			continue
+		}
+		// only the chained methods are left, called with a nil entry they return at their nil check
+		for call := range calls {
+			if !strings.HasPrefix(call, pkg+"(*Entry).") && !strings.HasPrefix(call, "runtime.") {
+				t.Errorf("go build -tags=%q: %s is called by the eliminated chains", c.Tags, call)
+			}
+		}
+	}
+}
+
+func TestLoggerSilentInline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiler check in short mode")
+	}
+
+	out, err := exec.Command("go", "build", "-gcflags=-m", "-o", os.DevNull, ".").CombinedOutput()
+	if err != nil {
+		t.Fatalf("go build error: %+v: %s", err, out)
+	}
+	// the level check of disabled logging is inlined into every Info, Warn, ... call
+	if !bytes.Contains(out, []byte(": can inline (*Logger).silent\n")) {
+		t.Errorf("(*Logger).silent should be inlined")
+	}
+}
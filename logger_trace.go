@@ -0,0 +1,36 @@
+//go:build !logstack_notrace && !logstack_nodebug
+// +build !logstack_notrace,!logstack_nodebug
+
+package log
+
+// Trace starts a new message with trace level.
+func Trace() (e *Entry) {
+	if DefaultLogger.silent(TraceLevel) {
+		return nil
+	}
+	e = DefaultLogger.header(TraceLevel)
+	if caller, full := DefaultLogger.Caller, false; caller != 0 {
+		if caller < 0 {
+			caller, full = -caller, true
+		}
+		var rpc [1]uintptr
+		e.caller(callers(caller, rpc[:]), rpc[:], full)
+	}
+	return
+}
+
+// Trace starts a new message with trace level.
+func (l *Logger) Trace() (e *Entry) {
+	if l.silent(TraceLevel) {
+		return nil
+	}
+	e = l.header(TraceLevel)
+	if caller, full := l.Caller, false; caller != 0 {
+		if caller < 0 {
+			caller, full = -caller, true
+		}
+		var rpc [1]uintptr
+		e.caller(callers(caller, rpc[:]), rpc[:], full)
+	}
+	return
+}
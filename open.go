@@ -0,0 +1,354 @@
+package log
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriterOpener creates a Writer from a parsed URI, see RegisterWriterScheme.
+type WriterOpener func(u *url.URL) (Writer, error)
+
+var writerSchemes = struct {
+	sync.RWMutex
+	m map[string]WriterOpener
+}{m: make(map[string]WriterOpener)}
+
+// RegisterWriterScheme makes a writer scheme available to OpenWriter.
+// It panics if the scheme is registered twice or the opener is nil.
+func RegisterWriterScheme(scheme string, opener WriterOpener) {
+	writerSchemes.Lock()
+	defer writerSchemes.Unlock()
+
+	scheme = strings.ToLower(scheme)
+	if opener == nil {
+		panic("log: RegisterWriterScheme opener is nil")
+	}
+	if _, dup := writerSchemes.m[scheme]; dup {
+		panic("log: RegisterWriterScheme called twice for scheme " + scheme)
+	}
+	writerSchemes.m[scheme] = opener
+}
+
+// OpenWriter creates a Writer from an URI, the query parameters map onto the fields of the writer.
+//
+//	file:///var/log/app.log?maxsize=100MB&backups=7   FileWriter, also mode, timeformat, localtime, hostname, pid and ensurefolder
+//	file:///var/log/app.log?gzip=true&segment=true     FileWriter, also flushinterval, maxsizecompressed, errorfile and errorlevel
+//	syslog+udp://host:514?tag=app                      SyslogWriter over udp, tcp, unix or unixgram, also hostname and marker
+//	syslog:///dev/log                                  SyslogWriter over udp with a host, or unixgram with a path, or the local socket if empty
+//	syslog+tls://host:6514?ca=/etc/ssl/ca.pem          SyslogWriter over TLS, also cert, key, servername and reload
+//	journal://                                         JournalWriter on linux, the path specifies the journal socket
+//	stderr://?format=console                           stderr or stdout with format json, console or logfmt, also color, quote and endwithmessage
+//	async+file:///var/log/app.log?channelsize=4096     AsyncWriter of any other scheme, also shards and strictorder
+//
+// Sizes accept the suffixes KB, MB and GB in units of 1024. Unknown parameters are an error.
+// The errorfile parameter is the filename of the ErrorFile, which keeps its other fields at
+// their defaults, and segment sets an empty Segment. Header and Cleaner have no parameter.
+func OpenWriter(uri string) (Writer, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("log: invalid writer uri %q: %w", uri, err)
+	}
+
+	if inner := strings.TrimPrefix(u.Scheme, "async+"); inner != u.Scheme {
+		return openAsyncWriter(u, inner)
+	}
+
+	writerSchemes.RLock()
+	opener := writerSchemes.m[strings.ToLower(u.Scheme)]
+	writerSchemes.RUnlock()
+	if opener == nil {
+		return nil, fmt.Errorf("log: unknown writer scheme %q", u.Scheme)
+	}
+
+	w, err := opener(u)
+	if err != nil {
+		return nil, fmt.Errorf("log: open writer %q: %w", uri, err)
+	}
+	return w, nil
+}
+
+func init() {
+	RegisterWriterScheme("file", openFileWriter)
+	for _, scheme := range []string{"syslog", "syslog+udp", "syslog+tcp", "syslog+unix", "syslog+unixgram", "syslog+tls"} {
+		RegisterWriterScheme(scheme, openSyslogWriter)
+	}
+	RegisterWriterScheme("stderr", openStdWriter)
+	RegisterWriterScheme("stdout", openStdWriter)
+}
+
+func openAsyncWriter(u *url.URL, inner string) (Writer, error) {
+	q := newWriterQuery(u)
+	w := &AsyncWriter{
+		ChannelSize: uint(q.int("channelsize")),
+		Shards:      q.int("shards"),
+		StrictOrder: q.bool("strictorder"),
+	}
+	if q.err != nil {
+		return nil, fmt.Errorf("log: open writer %q: %w", u.String(), q.err)
+	}
+
+	// the remaining parameters belong to the inner writer
+	values := u.Query()
+	for key := range q.used {
+		values.Del(key)
+	}
+	v := *u
+	v.Scheme = inner
+	v.RawQuery = values.Encode()
+
+	var err error
+	w.Writer, err = OpenWriter(v.String())
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func openFileWriter(u *url.URL) (Writer, error) {
+	filename := u.Opaque
+	if filename == "" {
+		// file://app.log is a relative filename
+		filename = u.Host + u.Path
+	}
+	if filename == "" {
+		return nil, errors.New("missing filename")
+	}
+
+	q := newWriterQuery(u)
+	w := &FileWriter{
+		Filename:          filename,
+		MaxSize:           q.size("maxsize"),
+		MaxBackups:        q.int("backups"),
+		FileMode:          os.FileMode(q.uint("mode", 8)),
+		TimeFormat:        q.str("timeformat"),
+		LocalTime:         q.bool("localtime"),
+		HostName:          q.bool("hostname"),
+		ProcessID:         q.bool("pid"),
+		EnsureFolder:      q.bool("ensurefolder"),
+		ErrorLevel:        q.level("errorlevel"),
+		Gzip:              q.bool("gzip"),
+		FlushInterval:     q.duration("flushinterval"),
+		MaxSizeCompressed: q.bool("maxsizecompressed"),
+	}
+	if name := q.str("errorfile"); name != "" {
+		w.ErrorFile = &FileWriter{Filename: name}
+	}
+	if q.bool("segment") {
+		w.Segment = &Segment{}
+	}
+	return w, q.done()
+}
+
+func openSyslogWriter(u *url.URL) (Writer, error) {
+	q := newWriterQuery(u)
+	w := &SyslogWriter{
+		Network:  strings.TrimPrefix(strings.TrimPrefix(u.Scheme, "syslog"), "+"),
+		Address:  u.Host,
+		Hostname: q.str("hostname"),
+		Tag:      q.str("tag"),
+		Marker:   q.str("marker"),
+	}
+	if u.Host == "" {
+		w.Address = u.Path
+	}
+	switch {
+	case w.Network == "tls":
+		w.Network = "tcp"
+		w.TLSConfig = &tls.Config{ServerName: q.str("servername")}
+		w.CAFile, w.CertFile, w.KeyFile = q.str("ca"), q.str("cert"), q.str("key")
+		w.ReloadInterval = q.duration("reload")
+	case w.Network == "" && w.Address == "":
+		// the local syslog socket
+		return w, q.done()
+	case w.Network == "":
+		w.Network = "udp"
+		if u.Host == "" {
+			w.Network = "unixgram"
+		}
+	}
+	if w.Address == "" {
+		return nil, errors.New("missing syslog address")
+	}
+	return w, q.done()
+}
+
+func openStdWriter(u *url.URL) (Writer, error) {
+	file := os.Stderr
+	if u.Scheme == "stdout" {
+		file = os.Stdout
+	}
+
+	q := newWriterQuery(u)
+	format := q.str("format")
+	console := &ConsoleWriter{
+		ColorOutput:    q.boolDefault("color", IsTerminal(file.Fd())),
+		QuoteString:    q.bool("quote"),
+		EndWithMessage: q.bool("endwithmessage"),
+		Writer:         file,
+	}
+	if err := q.done(); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "", "json":
+		return IOWriter{file}, nil
+	case "console":
+		return console, nil
+	case "logfmt":
+		console.Formatter = LogfmtFormatter{}.Formatter
+		return console, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// writerQuery parses the query parameters of a writer uri and reports the unknown and invalid ones.
+type writerQuery struct {
+	values url.Values
+	used   map[string]bool
+	err    error
+}
+
+func newWriterQuery(u *url.URL) *writerQuery {
+	return &writerQuery{values: u.Query(), used: make(map[string]bool)}
+}
+
+func (q *writerQuery) str(key string) string {
+	q.used[key] = true
+	return q.values.Get(key)
+}
+
+func (q *writerQuery) fail(key, value string, err error) {
+	if q.err == nil {
+		q.err = fmt.Errorf("invalid %s=%q: %w", key, value, err)
+	}
+}
+
+func (q *writerQuery) bool(key string) bool {
+	return q.boolDefault(key, false)
+}
+
+func (q *writerQuery) boolDefault(key string, value bool) bool {
+	s := q.str(key)
+	if s == "" {
+		return value
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		q.fail(key, s, err)
+	}
+	return b
+}
+
+func (q *writerQuery) int(key string) int {
+	s := q.str(key)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		q.fail(key, s, err)
+	}
+	return n
+}
+
+func (q *writerQuery) uint(key string, base int) uint64 {
+	s := q.str(key)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.ParseUint(s, base, 32)
+	if err != nil {
+		q.fail(key, s, err)
+	}
+	return n
+}
+
+func (q *writerQuery) duration(key string) time.Duration {
+	s := q.str(key)
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		q.fail(key, s, err)
+	}
+	return d
+}
+
+func (q *writerQuery) level(key string) Level {
+	s := q.str(key)
+	if s == "" {
+		return 0
+	}
+	level := ParseLevel(s)
+	if level == noLevel {
+		q.fail(key, s, errors.New("unknown level"))
+	}
+	return level
+}
+
+func (q *writerQuery) size(key string) int64 {
+	s := q.str(key)
+	if s == "" {
+		return 0
+	}
+	n, err := parseSize(s)
+	if err != nil {
+		q.fail(key, s, err)
+	}
+	return n
+}
+
+// done returns the first invalid parameter, or the unknown parameters.
+func (q *writerQuery) done() error {
+	if q.err != nil {
+		return q.err
+	}
+	var unknown []string
+	for key := range q.values {
+		if !q.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) != 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown parameters %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// parseSize parses a size in bytes with an optional suffix of KB, MB or GB in units of 1024.
+func parseSize(s string) (int64, error) {
+	unit := int64(1)
+	upper := strings.ToUpper(s)
+	for _, suffix := range []struct {
+		Name string
+		Unit int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(upper, suffix.Name) {
+			unit, s = suffix.Unit, strings.TrimSpace(s[:len(s)-len(suffix.Name)])
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, errors.New("negative size")
+	}
+	if n > math.MaxInt64/unit {
+		return 0, errors.New("size overflows int64")
+	}
+	return n * unit, nil
+}
@@ -0,0 +1,130 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+var errEncoding = errors.New("parquet: invalid encoded data")
+
+// bitWidth returns the number of bits to encode values up to max.
+func bitWidth(max uint64) int {
+	return bits.Len64(max)
+}
+
+// appendHybrid appends values in the RLE/bit-packing hybrid encoding, long repeats
+// are encoded as RLE runs and the others as bit-packed groups of 8 values.
+func appendHybrid(buf []byte, values []uint32, width int) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	header := func(v uint64) {
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+	}
+	repeats := func(i int) int {
+		n := 1
+		for i+n < len(values) && values[i+n] == values[i] {
+			n++
+		}
+		return n
+	}
+
+	for i := 0; i < len(values); {
+		if n := repeats(i); n >= 8 {
+			header(uint64(n) << 1)
+			for b := 0; b < (width+7)/8; b++ {
+				buf = append(buf, byte(values[i]>>(8*b)))
+			}
+			i += n
+			continue
+		}
+
+		start, groups := i, 0
+		for i < len(values) && repeats(i) < 8 {
+			i += 8
+			groups++
+		}
+		if i > len(values) {
+			i = len(values)
+		}
+		header(uint64(groups)<<1 | 1)
+
+		// bit-packed, the least significant bit first
+		var acc uint64
+		var nbits int
+		for j := 0; j < groups*8; j++ {
+			var v uint32
+			if start+j < i {
+				v = values[start+j]
+			}
+			acc |= uint64(v) << nbits
+			nbits += width
+			for nbits >= 8 {
+				buf = append(buf, byte(acc))
+				acc >>= 8
+				nbits -= 8
+			}
+		}
+	}
+
+	return buf
+}
+
+// readHybrid decodes n values in the RLE/bit-packing hybrid encoding, it returns the values and the rest of data.
+func readHybrid(data []byte, width, n int) ([]uint32, []byte, error) {
+	values := make([]uint32, 0, n)
+	for len(values) < n {
+		h, k := binary.Uvarint(data)
+		if k <= 0 {
+			return nil, nil, errEncoding
+		}
+		data = data[k:]
+
+		if h&1 == 0 {
+			count, size := int(h>>1), (width+7)/8
+			if len(data) < size || count > n-len(values) {
+				return nil, nil, errEncoding
+			}
+			var v uint32
+			for b := 0; b < size; b++ {
+				v |= uint32(data[b]) << (8 * b)
+			}
+			data = data[size:]
+			for j := 0; j < count; j++ {
+				values = append(values, v)
+			}
+			continue
+		}
+
+		count := int(h>>1) * 8
+		size := count * width / 8
+		if len(data) < size {
+			return nil, nil, errEncoding
+		}
+		var acc uint64
+		var nbits int
+		p := data[:size]
+		mask := uint64(1)<<width - 1
+		for j := 0; j < count; j++ {
+			for nbits < width {
+				acc |= uint64(p[0]) << nbits
+				p = p[1:]
+				nbits += 8
+			}
+			if len(values) < n {
+				values = append(values, uint32(acc&mask))
+			}
+			acc >>= width
+			nbits -= width
+		}
+		data = data[size:]
+	}
+	return values, data, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
@@ -0,0 +1,224 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+func readAll(t *testing.T, b []byte) (*Reader, []map[string]interface{}) {
+	t.Helper()
+	r, err := NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("parquet NewReader error: %+v", err)
+	}
+	rows, err := r.ReadRows()
+	if err != nil {
+		t.Fatalf("parquet ReadRows error: %+v", err)
+	}
+	if int64(len(rows)) != r.NumRows() {
+		t.Fatalf("parquet rows %d not equal to NumRows %d", len(rows), r.NumRows())
+	}
+	return r, rows
+}
+
+func TestWriterLogger(t *testing.T) {
+	var buf bytes.Buffer
+	w := &Writer{Writer: &buf, RowGroupSize: 3, SampleSize: 5}
+
+	logger := log.Logger{Level: log.InfoLevel, Writer: log.IOWriter{Writer: w}}
+	for i := 0; i < 10; i++ {
+		logger.Info().Int("n", i).Str("user", "alice").Bool("ok", i%2 == 0).Float64("ratio", 0.5).
+			Dict("req", log.NewContext(nil).Str("path", "/").Value()).Msgf("hello %d", i)
+	}
+	logger.Warn().Str("n", "not a number").Msg("mismatch")
+	if err := w.Close(); err != nil {
+		t.Fatalf("parquet Close error: %+v", err)
+	}
+
+	r, rows := readAll(t, buf.Bytes())
+	columns := []Column{
+		{"time", "timestamp"},
+		{"level", "string"},
+		{"message", "string"},
+		{"n", "int64"},
+		{"user", "string"},
+		{"ok", "bool"},
+		{"ratio", "double"},
+		{"req", "json"},
+		{"_extra", "json"},
+	}
+	if !reflect.DeepEqual(r.Columns(), columns) {
+		t.Fatalf("parquet columns not correct: %+v", r.Columns())
+	}
+	if len(rows) != 11 {
+		t.Fatalf("parquet rows not correct: %d", len(rows))
+	}
+
+	row := rows[7]
+	if ts, ok := row["time"].(time.Time); !ok || time.Since(ts) > time.Minute {
+		t.Errorf("parquet time not correct: %v", row["time"])
+	}
+	delete(row, "time")
+	want := map[string]interface{}{
+		"level":   "info",
+		"message": "hello 7",
+		"n":       int64(7),
+		"user":    "alice",
+		"ok":      false,
+		"ratio":   0.5,
+		"req":     json.RawMessage(`{"path":"/"}`),
+	}
+	if !reflect.DeepEqual(row, want) {
+		t.Errorf("parquet row not correct: %+v", row)
+	}
+
+	if extra, _ := rows[10]["_extra"].(json.RawMessage); string(extra) != `{"n":"not a number"}` {
+		t.Errorf("parquet extra not correct: %s", extra)
+	}
+	if _, ok := rows[10]["n"]; ok {
+		t.Errorf("parquet mismatched value should be null: %+v", rows[10])
+	}
+}
+
+func TestWriterInfer(t *testing.T) {
+	input := `{"time":1573071825.123,"level":"info","a":1,"b":"x","c":1,"d":null,"e":[1,2]}
+{"time":1573071826,"level":"????","a":1.5,"b":"2019-07-10T05:35:54.277Z","c":"y","a":2}
+not a json line
+`
+	var buf bytes.Buffer
+	w := &Writer{Writer: &buf, Compression: Gzip}
+	if _, err := w.Write([]byte(input)); err != nil {
+		t.Fatalf("parquet Write error: %+v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("parquet Close error: %+v", err)
+	}
+	if _, err := w.Write([]byte("{}\n")); err == nil {
+		t.Errorf("parquet Write after Close should return an error")
+	}
+
+	r, rows := readAll(t, buf.Bytes())
+	columns := []Column{
+		{"time", "timestamp"},
+		{"level", "string"},
+		{"a", "double"},
+		{"b", "string"},
+		{"c", "json"},
+		{"e", "json"},
+		{"_extra", "json"},
+	}
+	if !reflect.DeepEqual(r.Columns(), columns) {
+		t.Fatalf("parquet columns not correct: %+v", r.Columns())
+	}
+
+	want := []map[string]interface{}{
+		{
+			"time":  time.Date(2019, 11, 6, 20, 23, 45, 123000000, time.UTC),
+			"level": "info",
+			"a":     1.0,
+			"b":     "x",
+			"c":     json.RawMessage(`1`),
+			"e":     json.RawMessage(`[1,2]`),
+		},
+		{
+			"time":   time.Date(2019, 11, 6, 20, 23, 46, 0, time.UTC),
+			"a":      1.5,
+			"b":      "2019-07-10T05:35:54.277Z",
+			"c":      json.RawMessage(`"y"`),
+			"_extra": json.RawMessage(`{"a":2}`),
+		},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("parquet rows not correct: %+v", rows)
+	}
+}
+
+func TestWriterDictionary(t *testing.T) {
+	defer func(n int) { maxDictionaryBytes = n }(maxDictionaryBytes)
+
+	for _, size := range []int{defaultDictionaryBytes, 64} {
+		maxDictionaryBytes = size
+
+		var buf bytes.Buffer
+		w := &Writer{Writer: &buf, SampleSize: 10, RowGroupSize: 100}
+		for i := 0; i < 250; i++ {
+			_, _ = w.Write([]byte(`{"level":"info","message":"` + string(rune('a'+i%20)) + `"}` + "\n"))
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("parquet Close error: %+v", err)
+		}
+
+		_, rows := readAll(t, buf.Bytes())
+		if len(rows) != 250 {
+			t.Fatalf("parquet rows not correct: %d", len(rows))
+		}
+		for i, row := range rows {
+			if row["message"] != string(rune('a'+i%20)) || row["level"] != "info" {
+				t.Fatalf("parquet row %d not correct: %+v", i, row)
+			}
+		}
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w := &Writer{Writer: &buf}
+	if err := w.Close(); err != nil {
+		t.Fatalf("parquet Close error: %+v", err)
+	}
+	r, rows := readAll(t, buf.Bytes())
+	if len(rows) != 0 || len(r.Columns()) != 1 {
+		t.Errorf("parquet empty file not correct: %+v %+v", r.Columns(), rows)
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("write error") }
+
+func TestWriterError(t *testing.T) {
+	w := &Writer{Writer: errWriter{}, SampleSize: 1}
+	if _, err := w.Write([]byte(`{"level":"info"}` + "\n")); err != nil {
+		t.Fatalf("parquet Write should buffer the rows: %+v", err)
+	}
+	if err := w.Close(); err == nil || err.Error() != "write error" {
+		t.Errorf("parquet Close error not correct: %+v", err)
+	}
+}
+
+func TestReaderInvalid(t *testing.T) {
+	for _, b := range []string{"", "PAR1PAR1", "PAR1\x00\x00\x00\x00\xff\x00\x00\x00PAR1", "PAR1\x01\x00\x00\x00PAR2"} {
+		if _, err := NewReader(bytes.NewReader([]byte(b)), int64(len(b))); err == nil {
+			t.Errorf("parquet NewReader(%q) should return an error", b)
+		}
+	}
+}
+
+func TestHybrid(t *testing.T) {
+	cases := []struct {
+		Values []uint32
+		Width  int
+	}{
+		{[]uint32{}, 1},
+		{[]uint32{1, 0, 1}, 1},
+		{[]uint32{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 1, 0}, 1},
+		{[]uint32{3, 3, 3, 3, 3, 3, 3, 3, 5, 6, 7, 0, 1, 2, 300, 300}, 9},
+		{[]uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 9}, 4},
+	}
+	for _, c := range cases {
+		data := appendHybrid(nil, c.Values, c.Width)
+		values, rest, err := readHybrid(append(data, 0xff), c.Width, len(c.Values))
+		if err != nil {
+			t.Fatalf("readHybrid(%v) error: %+v", c.Values, err)
+		}
+		if !reflect.DeepEqual(values, c.Values) || !bytes.Equal(rest, []byte{0xff}) {
+			t.Errorf("readHybrid(%v) not correct: %v %v", c.Values, values, rest)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Column describes a column of a Parquet file.
+type Column struct {
+	// Name is the name of the column.
+	Name string
+
+	// Type is one of "string", "json", "int64", "double", "bool" and "timestamp".
+	Type string
+}
+
+// Reader reads the Parquet files written by Writer.
+type Reader struct {
+	r       io.ReaderAt
+	columns []Column
+	kinds   []int
+	meta    thriftStructValue
+}
+
+var errFormat = errors.New("parquet: invalid file format")
+
+// NewReader reads the file metadata of a Parquet file of size bytes.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	if size < 12 {
+		return nil, errFormat
+	}
+	var tail [8]byte
+	if _, err := r.ReadAt(tail[:], size-8); err != nil {
+		return nil, err
+	}
+	n := int64(binary.LittleEndian.Uint32(tail[:4]))
+	if !bytes.Equal(tail[4:], magic) || n > size-12 {
+		return nil, errFormat
+	}
+
+	footer := make([]byte, n)
+	if _, err := r.ReadAt(footer, size-8-n); err != nil {
+		return nil, err
+	}
+	meta, err := (&thriftReader{bytes.NewReader(footer)}).readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("parquet: invalid file metadata: %w", err)
+	}
+
+	reader := &Reader{r: r, meta: meta}
+	schema := meta.list(2)
+	if len(schema) == 0 {
+		return nil, errFormat
+	}
+	for _, v := range schema[1:] {
+		element, _ := v.(thriftStructValue)
+		kind := columnKind(element)
+		if kind == 0 {
+			return nil, fmt.Errorf("parquet: unsupported column %q", element.str(4))
+		}
+		reader.columns = append(reader.columns, Column{Name: element.str(4), Type: kindNames[kind]})
+		reader.kinds = append(reader.kinds, kind)
+	}
+	return reader, nil
+}
+
+var kindNames = map[int]string{
+	kindString:    "string",
+	kindJSON:      "json",
+	kindInt64:     "int64",
+	kindDouble:    "double",
+	kindBool:      "bool",
+	kindTimestamp: "timestamp",
+}
+
+func columnKind(element thriftStructValue) int {
+	converted, ok := element[6].(int64)
+	switch element.i64(1) {
+	case typeByteArray:
+		if ok && converted == convertedJSON {
+			return kindJSON
+		}
+		return kindString
+	case typeInt64:
+		if ok && converted == convertedTimestampMicros {
+			return kindTimestamp
+		}
+		return kindInt64
+	case typeDouble:
+		return kindDouble
+	case typeBoolean:
+		return kindBool
+	}
+	return 0
+}
+
+// Columns returns the columns of the file.
+func (r *Reader) Columns() []Column {
+	return r.columns
+}
+
+// NumRows returns the number of rows of the file.
+func (r *Reader) NumRows() int64 {
+	return r.meta.i64(3)
+}
+
+// ReadRows reads all rows of the file, the null values are omitted from the rows.
+// Timestamps are read as time.Time in UTC and json columns as json.RawMessage.
+func (r *Reader) ReadRows() ([]map[string]interface{}, error) {
+	rows := make([]map[string]interface{}, 0, r.NumRows())
+	for _, v := range r.meta.list(4) {
+		group, _ := v.(thriftStructValue)
+		chunks := group.list(1)
+		if len(chunks) != len(r.columns) {
+			return nil, errFormat
+		}
+		start := len(rows)
+		for i := int64(0); i < group.i64(3); i++ {
+			rows = append(rows, make(map[string]interface{}))
+		}
+		for i, v := range chunks {
+			chunk, _ := v.(thriftStructValue)
+			values, err := r.readChunk(chunk.strct(3), r.kinds[i])
+			if err != nil {
+				return nil, fmt.Errorf("parquet: column %q: %w", r.columns[i].Name, err)
+			}
+			if len(values) != len(rows)-start {
+				return nil, errFormat
+			}
+			for j, value := range values {
+				if value != nil {
+					rows[start+j][r.columns[i].Name] = value
+				}
+			}
+		}
+	}
+	return rows, nil
+}
+
+// readChunk reads the values of a column chunk, nil for the null values.
+func (r *Reader) readChunk(meta thriftStructValue, kind int) ([]interface{}, error) {
+	offset := meta.i64(9)
+	if _, ok := meta[11].(int64); ok {
+		offset = meta.i64(11)
+	}
+	size := meta.i64(7)
+	if offset < 0 || size < 0 || size > 1<<31 {
+		return nil, errFormat
+	}
+	data := make([]byte, size)
+	if _, err := r.r.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+
+	br := bytes.NewReader(data)
+	var dict []interface{}
+	var values []interface{}
+	for int64(len(values)) < meta.i64(5) {
+		header, err := (&thriftReader{br}).readStruct()
+		if err != nil {
+			return nil, err
+		}
+		n := header.i64(3)
+		if n < 0 || n > int64(br.Len()) {
+			return nil, errFormat
+		}
+		body := make([]byte, n)
+		_, _ = br.Read(body)
+		if meta.i64(4) == int64(Gzip) {
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			if body, err = io.ReadAll(zr); err != nil {
+				return nil, err
+			}
+		} else if meta.i64(4) != int64(Uncompressed) {
+			return nil, fmt.Errorf("unsupported compression codec %d", meta.i64(4))
+		}
+
+		switch header.i64(1) {
+		case pageDictionary:
+			dict, err = readPlain(body, kind, int(header.strct(7).i64(1)))
+			if err != nil {
+				return nil, err
+			}
+		case pageData:
+			page := header.strct(5)
+			if values, err = readDataPage(values, body, kind, int(page.i64(1)), page.i64(2), dict); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported page type %d", header.i64(1))
+		}
+	}
+	return values, nil
+}
+
+// readDataPage appends the values of a data page v1 of an optional column.
+func readDataPage(values []interface{}, body []byte, kind, n int, encoding int64, dict []interface{}) ([]interface{}, error) {
+	if len(body) < 4 {
+		return nil, errEncoding
+	}
+	size := int(binary.LittleEndian.Uint32(body))
+	if size > len(body)-4 {
+		return nil, errEncoding
+	}
+	defs, _, err := readHybrid(body[4:4+size], 1, n)
+	if err != nil {
+		return nil, err
+	}
+	body = body[4+size:]
+	count := 0
+	for _, d := range defs {
+		count += int(d)
+	}
+
+	var present []interface{}
+	switch encoding {
+	case encodingPlain:
+		if present, err = readPlain(body, kind, count); err != nil {
+			return nil, err
+		}
+	case encodingRLEDictionary, encodingPlainDict:
+		if len(body) < 1 {
+			return nil, errEncoding
+		}
+		indexes, _, err := readHybrid(body[1:], int(body[0]), count)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range indexes {
+			if int(i) >= len(dict) {
+				return nil, errEncoding
+			}
+			present = append(present, dict[i])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %d", encoding)
+	}
+
+	for _, d := range defs {
+		if d == 0 {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, present[0])
+		present = present[1:]
+	}
+	return values, nil
+}
+
+// readPlain decodes n values in the plain encoding.
+func readPlain(data []byte, kind, n int) ([]interface{}, error) {
+	values := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		switch kind {
+		case kindInt64, kindTimestamp, kindDouble:
+			if len(data) < 8 {
+				return nil, errEncoding
+			}
+			v := binary.LittleEndian.Uint64(data)
+			data = data[8:]
+			switch kind {
+			case kindInt64:
+				values = append(values, int64(v))
+			case kindDouble:
+				values = append(values, math.Float64frombits(v))
+			default:
+				values = append(values, time.UnixMicro(int64(v)).UTC())
+			}
+		case kindBool:
+			if len(data) < (n+7)/8 {
+				return nil, errEncoding
+			}
+			values = append(values, data[i/8]&(1<<(i%8)) != 0)
+		default:
+			if len(data) < 4 {
+				return nil, errEncoding
+			}
+			size := int(binary.LittleEndian.Uint32(data))
+			if size > len(data)-4 {
+				return nil, errEncoding
+			}
+			b := data[4 : 4+size]
+			data = data[4+size:]
+			if kind == kindJSON {
+				values = append(values, json.RawMessage(b))
+			} else {
+				values = append(values, string(b))
+			}
+		}
+	}
+	return values, nil
+}
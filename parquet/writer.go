@@ -0,0 +1,686 @@
+// Package parquet converts the JSON logs of github.com/fabricatorsltd/logstack to Parquet files
+// without external dependencies, and reads them back.
+//
+// The schema is inferred from the leading entries, nested objects and arrays are stored as JSON
+// strings, and the fields which are unknown to the schema or do not match its types are stored
+// in the JSON object column "_extra".
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+// Compression specifies the compression codec of the pages.
+type Compression int
+
+const (
+	// Uncompressed writes the pages uncompressed.
+	Uncompressed Compression = 0
+	// Gzip compresses the pages by gzip.
+	Gzip Compression = 2
+)
+
+// ExtraColumn is the name of the column storing the fields unknown to the schema.
+const ExtraColumn = "_extra"
+
+// Writer is an io.Writer that converts JSON log entries to a Parquet file,
+// every Write must contain complete entries separated by newlines.
+// Close must be called to write the file footer.
+type Writer struct {
+	// Writer specifies the output destination.
+	Writer io.Writer
+
+	// RowGroupSize specifies the number of rows of a row group, the default is 65536.
+	RowGroupSize int
+
+	// SampleSize specifies the number of leading entries to infer the schema from, the default is 1000.
+	SampleSize int
+
+	// Compression specifies the compression codec of the pages.
+	Compression Compression
+
+	columns []*column
+	index   map[string]*column
+	extra   *column
+	samples [][]field
+	rows    int
+	numRows int64
+	offset  int64
+	groups  [][]chunkMeta
+	err     error
+}
+
+type field struct {
+	key   string
+	value string
+	typ   byte
+}
+
+// kinds of columns
+const (
+	kindString = iota + 1
+	kindJSON
+	kindInt64
+	kindDouble
+	kindBool
+	kindTimestamp
+)
+
+// physical types, converted types and encodings of the parquet format
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+	convertedJSON            = 19
+
+	encodingPlain          = 0
+	encodingPlainDict      = 2
+	encodingRLE            = 3
+	encodingRLEDictionary  = 8
+	pageData               = 0
+	pageDictionary         = 2
+	repetitionOptional     = 1
+	maxDictionaryEntries   = 1 << 16
+	defaultRowGroupSize    = 65536
+	defaultSampleSize      = 1000
+	defaultDictionaryBytes = 1 << 20
+)
+
+var magic = []byte("PAR1")
+
+// maxDictionaryBytes is the dictionary size of a column chunk above which its values are plain encoded.
+var maxDictionaryBytes = defaultDictionaryBytes
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	for _, line := range bytes.Split(p, []byte{'\n'}) {
+		if line = bytes.TrimSpace(line); len(line) == 0 || line[0] != '{' || log.IsSegmentMarker(line) {
+			continue
+		}
+		w.writeEntry(append([]byte(nil), line...))
+		if w.err != nil {
+			return 0, w.err
+		}
+	}
+	return len(p), nil
+}
+
+// Close writes the buffered rows and the file footer, it does not close the underlying Writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.index == nil {
+		w.infer()
+	}
+	if w.rows > 0 {
+		w.flush()
+	}
+	if w.err == nil {
+		w.footer()
+	}
+	if w.err == nil {
+		w.err = errors.New("parquet: writer is closed")
+		return nil
+	}
+	return w.err
+}
+
+func (w *Writer) writeEntry(line []byte) {
+	var args log.FormatterArgs
+	log.ParseFormatterArgs(line, &args, nil)
+	if args.Level == "????" {
+		args.Level = ""
+	}
+
+	fields := make([]field, 0, 6+len(args.KeyValues))
+	for _, kv := range []struct{ Key, Value string }{
+		{"time", args.Time},
+		{"level", args.Level},
+		{"caller", args.Caller},
+		{"goid", args.Goid},
+		{"stack", args.Stack},
+		{"message", args.Message},
+	} {
+		if kv.Value == "" {
+			continue
+		}
+		typ := byte('s')
+		if kv.Key == "time" || kv.Key == "goid" {
+			if _, err := strconv.ParseFloat(kv.Value, 64); err == nil {
+				typ = 'n'
+			}
+		}
+		fields = append(fields, field{kv.Key, kv.Value, typ})
+	}
+	for _, kv := range args.KeyValues {
+		fields = append(fields, field{kv.Key, kv.Value, kv.ValueType})
+	}
+
+	if w.index == nil {
+		w.samples = append(w.samples, fields)
+		if len(w.samples) >= w.sampleSize() {
+			w.infer()
+		}
+		return
+	}
+	w.addRow(fields)
+}
+
+func (w *Writer) sampleSize() int {
+	if w.SampleSize > 0 {
+		return w.SampleSize
+	}
+	return defaultSampleSize
+}
+
+// infer builds the schema from the sampled entries and adds them as rows.
+func (w *Writer) infer() {
+	w.index = make(map[string]*column)
+	for _, fields := range w.samples {
+		for _, f := range fields {
+			kind := fieldKind(f)
+			if kind == 0 || f.key == ExtraColumn {
+				continue
+			}
+			c := w.index[f.key]
+			if c == nil {
+				c = &column{name: f.key, kind: kind}
+				w.index[f.key] = c
+				w.columns = append(w.columns, c)
+				continue
+			}
+			c.kind = mergeKind(c.kind, kind)
+		}
+	}
+	w.extra = &column{name: ExtraColumn, kind: kindJSON}
+	w.columns = append(w.columns, w.extra)
+
+	samples := w.samples
+	w.samples = nil
+	for _, fields := range samples {
+		w.addRow(fields)
+	}
+}
+
+func fieldKind(f field) int {
+	switch f.typ {
+	case 's', 'S':
+		if f.key == "time" {
+			if _, ok := parseTime(f); ok {
+				return kindTimestamp
+			}
+		}
+		return kindString
+	case 'n':
+		if f.key == "time" {
+			if _, ok := parseTime(f); ok {
+				return kindTimestamp
+			}
+		}
+		if _, err := strconv.ParseInt(f.value, 10, 64); err == nil {
+			return kindInt64
+		}
+		return kindDouble
+	case 't', 'f':
+		return kindBool
+	case 'o':
+		return kindJSON
+	}
+	return 0
+}
+
+func mergeKind(a, b int) int {
+	switch {
+	case a == b:
+		return a
+	case a == kindInt64 && b == kindDouble, a == kindDouble && b == kindInt64:
+		return kindDouble
+	case a == kindTimestamp && b == kindString, a == kindString && b == kindTimestamp:
+		return kindString
+	}
+	return kindJSON
+}
+
+// parseTime parses a RFC3339 time string or an epoch number in seconds, milliseconds, microseconds or nanoseconds.
+func parseTime(f field) (time.Time, bool) {
+	if f.typ != 'n' {
+		t, err := time.Parse(time.RFC3339Nano, f.value)
+		return t, err == nil
+	}
+	v, err := strconv.ParseFloat(f.value, 64)
+	if err != nil || v <= 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case v < 1e11:
+		sec, frac := math.Modf(v)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e6))*1000), true
+	case v < 1e14:
+		return time.UnixMilli(int64(v)), true
+	case v < 1e17:
+		return time.UnixMicro(int64(v)), true
+	}
+	n, err := strconv.ParseInt(f.value, 10, 64)
+	return time.Unix(0, n), err == nil
+}
+
+func (w *Writer) addRow(fields []field) {
+	w.rows++
+	var extra []field
+	for _, f := range fields {
+		if f.typ == 0 {
+			// null
+			continue
+		}
+		c := w.index[f.key]
+		if c == nil || c.rows == w.rows || !c.add(f) {
+			extra = append(extra, f)
+			continue
+		}
+		c.rows = w.rows
+	}
+	for _, c := range w.columns {
+		if c.rows != w.rows && c != w.extra {
+			c.null()
+			c.rows = w.rows
+		}
+	}
+
+	if len(extra) == 0 {
+		w.extra.null()
+	} else {
+		b := []byte{'{'}
+		for i, f := range extra {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			key, _ := json.Marshal(f.key)
+			b = append(b, key...)
+			b = append(b, ':')
+			b = append(b, jsonValue(f)...)
+		}
+		b = append(b, '}')
+		w.extra.bytes(b)
+	}
+	w.extra.rows = w.rows
+
+	size := w.RowGroupSize
+	if size <= 0 {
+		size = defaultRowGroupSize
+	}
+	if w.rows >= size {
+		w.flush()
+	}
+}
+
+// jsonValue returns the field as JSON text.
+func jsonValue(f field) string {
+	switch f.typ {
+	case 's', 'S':
+		b, _ := json.Marshal(f.value)
+		return string(b)
+	case 0:
+		return "null"
+	}
+	return f.value
+}
+
+// column buffers the values of a column for the current row group.
+type column struct {
+	name  string
+	kind  int
+	rows  int
+	defs  []uint32
+	strs  []string
+	ints  []int64
+	dbls  []float64
+	bools []bool
+}
+
+func (c *column) null() {
+	c.defs = append(c.defs, 0)
+}
+
+func (c *column) bytes(b []byte) {
+	c.defs = append(c.defs, 1)
+	c.strs = append(c.strs, string(b))
+}
+
+// add appends the field value, it returns false if the value does not match the column kind.
+func (c *column) add(f field) bool {
+	switch c.kind {
+	case kindString:
+		if f.typ != 's' && f.typ != 'S' {
+			return false
+		}
+		c.strs = append(c.strs, f.value)
+	case kindJSON:
+		c.strs = append(c.strs, jsonValue(f))
+	case kindInt64:
+		if f.typ != 'n' {
+			return false
+		}
+		v, err := strconv.ParseInt(f.value, 10, 64)
+		if err != nil {
+			return false
+		}
+		c.ints = append(c.ints, v)
+	case kindDouble:
+		if f.typ != 'n' {
+			return false
+		}
+		v, err := strconv.ParseFloat(f.value, 64)
+		if err != nil {
+			return false
+		}
+		c.dbls = append(c.dbls, v)
+	case kindBool:
+		if f.typ != 't' && f.typ != 'f' {
+			return false
+		}
+		c.bools = append(c.bools, f.typ == 't')
+	case kindTimestamp:
+		t, ok := parseTime(f)
+		if !ok {
+			return false
+		}
+		c.ints = append(c.ints, t.UnixMicro())
+	}
+	c.defs = append(c.defs, 1)
+	return true
+}
+
+func (c *column) reset() {
+	c.rows = 0
+	c.defs = c.defs[:0]
+	c.strs = c.strs[:0]
+	c.ints = c.ints[:0]
+	c.dbls = c.dbls[:0]
+	c.bools = c.bools[:0]
+}
+
+func (c *column) physicalType() int32 {
+	switch c.kind {
+	case kindInt64, kindTimestamp:
+		return typeInt64
+	case kindDouble:
+		return typeDouble
+	case kindBool:
+		return typeBoolean
+	}
+	return typeByteArray
+}
+
+// chunkMeta is the metadata of a written column chunk.
+type chunkMeta struct {
+	typ          int32
+	encodings    []int32
+	numValues    int64
+	uncompressed int64
+	compressed   int64
+	dataOffset   int64
+	dictOffset   int64
+	name         string
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	if w.offset == 0 {
+		if _, w.err = w.Writer.Write(magic); w.err != nil {
+			return
+		}
+		w.offset = int64(len(magic))
+	}
+	var n int
+	n, w.err = w.Writer.Write(b)
+	w.offset += int64(n)
+}
+
+// flush writes the buffered rows as a row group.
+func (w *Writer) flush() {
+	metas := make([]chunkMeta, 0, len(w.columns))
+	for _, c := range w.columns {
+		metas = append(metas, w.writeChunk(c))
+		c.reset()
+	}
+	w.groups = append(w.groups, metas)
+	w.numRows += int64(w.rows)
+	w.rows = 0
+}
+
+func (w *Writer) writeChunk(c *column) chunkMeta {
+	meta := chunkMeta{
+		typ:        c.physicalType(),
+		numValues:  int64(len(c.defs)),
+		dictOffset: -1,
+		name:       c.name,
+	}
+	if w.offset == 0 {
+		w.write(nil)
+	}
+
+	// definition levels
+	var defs []byte
+	defs = appendHybrid(defs, c.defs, 1)
+	body := appendUint32(nil, uint32(len(defs)))
+	body = append(body, defs...)
+
+	encoding := int32(encodingPlain)
+	if meta.typ == typeByteArray {
+		if dict, indexes, ok := dictionary(c.strs); ok && len(dict) > 0 {
+			encoding = encodingRLEDictionary
+			meta.dictOffset = w.offset
+			var page []byte
+			for _, s := range dict {
+				page = appendUint32(page, uint32(len(s)))
+				page = append(page, s...)
+			}
+			w.writePage(&meta, pageDictionary, len(dict), encodingPlain, page)
+
+			width := bitWidth(uint64(len(dict) - 1))
+			if width == 0 {
+				width = 1
+			}
+			body = append(body, byte(width))
+			body = appendHybrid(body, indexes, width)
+		}
+	}
+	if encoding == encodingPlain {
+		body = c.appendPlain(body)
+	}
+
+	meta.dataOffset = w.offset
+	w.writePage(&meta, pageData, len(c.defs), encoding, body)
+	if encoding == encodingRLEDictionary {
+		meta.encodings = []int32{encodingPlain, encodingRLE, encodingRLEDictionary}
+	} else {
+		meta.encodings = []int32{encodingPlain, encodingRLE}
+	}
+	return meta
+}
+
+// dictionary returns the distinct values and the indexes of values, it returns false if the dictionary is too large.
+func dictionary(values []string) ([]string, []uint32, bool) {
+	index := make(map[string]uint32)
+	dict := make([]string, 0)
+	indexes := make([]uint32, len(values))
+	size := 0
+	for i, s := range values {
+		n, ok := index[s]
+		if !ok {
+			if size += len(s) + 4; size > maxDictionaryBytes || len(dict) >= maxDictionaryEntries {
+				return nil, nil, false
+			}
+			n = uint32(len(dict))
+			index[s] = n
+			dict = append(dict, s)
+		}
+		indexes[i] = n
+	}
+	return dict, indexes, true
+}
+
+func (c *column) appendPlain(b []byte) []byte {
+	switch c.kind {
+	case kindInt64, kindTimestamp:
+		for _, v := range c.ints {
+			b = appendUint64(b, uint64(v))
+		}
+	case kindDouble:
+		for _, v := range c.dbls {
+			b = appendUint64(b, math.Float64bits(v))
+		}
+	case kindBool:
+		for i := 0; i < len(c.bools); i += 8 {
+			var v byte
+			for j := 0; j < 8 && i+j < len(c.bools); j++ {
+				if c.bools[i+j] {
+					v |= 1 << j
+				}
+			}
+			b = append(b, v)
+		}
+	default:
+		for _, s := range c.strs {
+			b = appendUint32(b, uint32(len(s)))
+			b = append(b, s...)
+		}
+	}
+	return b
+}
+
+// writePage writes a page with its header, and adds the sizes to the chunk metadata.
+func (w *Writer) writePage(meta *chunkMeta, typ int32, numValues int, encoding int32, body []byte) {
+	compressed := body
+	if w.Compression == Gzip {
+		var b bytes.Buffer
+		zw := gzip.NewWriter(&b)
+		_, _ = zw.Write(body)
+		_ = zw.Close()
+		compressed = b.Bytes()
+	}
+
+	var t thriftWriter
+	t.structBegin()
+	t.fieldI32(1, typ)
+	t.fieldI32(2, int32(len(body)))
+	t.fieldI32(3, int32(len(compressed)))
+	if typ == pageDictionary {
+		t.fieldStruct(7)
+		t.fieldI32(1, int32(numValues))
+		t.fieldI32(2, encoding)
+		t.structEnd()
+	} else {
+		t.fieldStruct(5)
+		t.fieldI32(1, int32(numValues))
+		t.fieldI32(2, encoding)
+		t.fieldI32(3, encodingRLE)
+		t.fieldI32(4, encodingRLE)
+		t.structEnd()
+	}
+	t.structEnd()
+
+	w.write(t.buf)
+	w.write(compressed)
+	meta.uncompressed += int64(len(t.buf) + len(body))
+	meta.compressed += int64(len(t.buf) + len(compressed))
+}
+
+// footer writes the file metadata.
+func (w *Writer) footer() {
+	if w.offset == 0 {
+		w.write(nil)
+	}
+
+	var t thriftWriter
+	t.structBegin()
+	t.fieldI32(1, 1)
+
+	// schema
+	t.fieldList(2, thriftStruct, len(w.columns)+1)
+	t.structBegin()
+	t.fieldBinary(4, "schema")
+	t.fieldI32(5, int32(len(w.columns)))
+	t.structEnd()
+	for _, c := range w.columns {
+		t.structBegin()
+		t.fieldI32(1, c.physicalType())
+		t.fieldI32(3, repetitionOptional)
+		t.fieldBinary(4, c.name)
+		switch c.kind {
+		case kindString:
+			t.fieldI32(6, convertedUTF8)
+		case kindJSON:
+			t.fieldI32(6, convertedJSON)
+		case kindTimestamp:
+			t.fieldI32(6, convertedTimestampMicros)
+		}
+		t.structEnd()
+	}
+
+	t.fieldI64(3, w.numRows)
+
+	// row groups
+	t.fieldList(4, thriftStruct, len(w.groups))
+	for _, metas := range w.groups {
+		var size int64
+		t.structBegin()
+		t.fieldList(1, thriftStruct, len(metas))
+		for _, m := range metas {
+			offset := m.dataOffset
+			if m.dictOffset >= 0 {
+				offset = m.dictOffset
+			}
+			t.structBegin()
+			t.fieldI64(2, offset)
+			t.fieldStruct(3)
+			t.fieldI32(1, m.typ)
+			t.fieldList(2, thriftI32, len(m.encodings))
+			for _, e := range m.encodings {
+				t.zigzag(int64(e))
+			}
+			t.fieldList(3, thriftBinary, 1)
+			t.binary(m.name)
+			t.fieldI32(4, int32(w.Compression))
+			t.fieldI64(5, m.numValues)
+			t.fieldI64(6, m.uncompressed)
+			t.fieldI64(7, m.compressed)
+			t.fieldI64(9, m.dataOffset)
+			if m.dictOffset >= 0 {
+				t.fieldI64(11, m.dictOffset)
+			}
+			t.structEnd()
+			t.structEnd()
+			size += m.uncompressed
+		}
+		t.fieldI64(2, size)
+		t.fieldI64(3, metas[0].numValues)
+		t.structEnd()
+	}
+
+	t.fieldBinary(6, "logstack parquet")
+	t.structEnd()
+
+	w.write(t.buf)
+	w.write(appendUint32(nil, uint32(len(t.buf))))
+	w.write(magic)
+}
@@ -0,0 +1,482 @@
+// Package report generates self-contained HTML incident reports from the JSON logs of
+// github.com/fabricatorsltd/logstack.
+//
+// A report covers a time window of one or more log sources, with a summary header, the entries
+// per level over time as an inline SVG chart, the top error messages and callers, and a
+// searchable table of the entries with their JSON. It references no external assets, and
+// the output depends only on the input, so that it can be attached to an incident document
+// or compared in tests.
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+// Report collects the entries of a time window from log sources and writes them as an HTML report.
+// The entries of the window are held in memory until the report is written.
+type Report struct {
+	// Title specifies the title of the report, the default is "Incident report".
+	Title string
+
+	// Start specifies the inclusive start of the window, the zero value is unbounded.
+	Start time.Time
+
+	// End specifies the exclusive end of the window, the zero value is unbounded.
+	End time.Time
+
+	// Buckets specifies the number of bars of the chart, the default is 60.
+	Buckets int
+
+	// TopN specifies the number of top error messages and callers, the default is 10.
+	TopN int
+
+	// MaxEntries specifies the maximum number of rows of the entries table, the default is 10000.
+	// The chart, the summary and the top lists always cover all entries of the window.
+	MaxEntries int
+
+	// Aliases specifies the optional keys of well-known fields, e.g. log.AutoFieldAliases,
+	// for reporting the JSON logs of other logging libraries.
+	Aliases *log.FieldAliases
+
+	sources []source
+	entries []entry
+	skipped int
+}
+
+type source struct {
+	Name    string
+	Entries int
+}
+
+type entry struct {
+	time    time.Time
+	level   log.Level
+	source  int
+	text    string
+	caller  string
+	message string
+	raw     []byte
+}
+
+// Add reads the JSON log lines of a source. The lines outside of the window and the segment markers
+// are ignored, and the lines which are not JSON or have no valid RFC3339 time are counted as skipped.
+func (r *Report) Add(name string, src io.Reader) error {
+	index := len(r.sources)
+	r.sources = append(r.sources, source{Name: name})
+
+	br := bufio.NewReaderSize(src, 64*1024)
+	for {
+		line, err := br.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			r.add(index, line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Report) add(index int, line []byte) {
+	if log.IsSegmentMarker(line) {
+		return
+	}
+	if line[0] != '{' {
+		r.skipped++
+		return
+	}
+
+	// the args reference the buffer which is unescaped in place, keep the line intact
+	raw := append([]byte(nil), line...)
+	var args log.FormatterArgs
+	log.ParseFormatterArgs(append([]byte(nil), line...), &args, r.Aliases)
+
+	t, err := time.Parse(time.RFC3339Nano, args.Time)
+	if err != nil {
+		r.skipped++
+		return
+	}
+	if (!r.Start.IsZero() && t.Before(r.Start)) || (!r.End.IsZero() && !t.Before(r.End)) {
+		return
+	}
+
+	r.sources[index].Entries++
+	r.entries = append(r.entries, entry{
+		time:    t,
+		level:   log.ParseLevel(args.Level),
+		source:  index,
+		text:    args.Time,
+		caller:  args.Caller,
+		message: args.Message,
+		raw:     raw,
+	})
+}
+
+// levels are the levels of the chart and the summary, the entries of unknown levels are reported as "????".
+var levels = []struct {
+	Level log.Level
+	Color string
+}{
+	{log.TraceLevel, "#9e9e9e"},
+	{log.DebugLevel, "#5b8bd0"},
+	{log.InfoLevel, "#3c9d50"},
+	{log.WarnLevel, "#e0a800"},
+	{log.ErrorLevel, "#d9534f"},
+	{log.FatalLevel, "#a0262b"},
+	{log.PanicLevel, "#5c0a12"},
+	{log.Level(0), "#c8c8c8"},
+}
+
+// levelIndex returns the index of a level in levels.
+func levelIndex(level log.Level) int {
+	if level >= log.TraceLevel && level <= log.PanicLevel {
+		return int(level - log.TraceLevel)
+	}
+	return len(levels) - 1
+}
+
+func isError(level log.Level) bool {
+	return level >= log.ErrorLevel && level <= log.PanicLevel
+}
+
+const (
+	chartBarWidth = 16
+	chartHeight   = 160
+	timeLayout    = "2006-01-02T15:04:05.000Z07:00"
+)
+
+type levelCount struct {
+	Name  string
+	Color string
+	Count int
+}
+
+type rect struct {
+	X, Y, Width, Height int
+	Color               string
+	Title               string
+}
+
+type topItem struct {
+	Name  string
+	Count int
+}
+
+type row struct {
+	Time    string
+	Level   string
+	Source  string
+	Caller  string
+	Message string
+	JSON    string
+}
+
+type page struct {
+	Title      string
+	Window     string
+	Sources    []source
+	Total      int
+	Skipped    int
+	First      string
+	Last       string
+	Levels     []levelCount
+	Width      int
+	Height     int
+	Max        int
+	ChartStart string
+	ChartEnd   string
+	Bars       []rect
+	Messages   []topItem
+	Callers    []topItem
+	Rows       []row
+	Omitted    int
+}
+
+// WriteHTML writes the report of the entries added so far as a self-contained HTML document.
+func (r *Report) WriteHTML(w io.Writer) error {
+	if len(r.sources) == 0 {
+		return errors.New("report: no log source added")
+	}
+
+	buckets, topN, maxEntries := r.Buckets, r.TopN, r.MaxEntries
+	if buckets <= 0 {
+		buckets = 60
+	}
+	if topN <= 0 {
+		topN = 10
+	}
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+
+	// the entries of the same time keep the order of sources and lines
+	entries := make([]entry, len(r.entries))
+	copy(entries, r.entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].time.Before(entries[j].time)
+	})
+
+	p := page{
+		Title:   r.Title,
+		Sources: r.sources,
+		Total:   len(entries),
+		Skipped: r.skipped,
+		Width:   buckets * chartBarWidth,
+		Height:  chartHeight,
+	}
+	if p.Title == "" {
+		p.Title = "Incident report"
+	}
+	p.Window = formatTime(r.Start, "unbounded") + " to " + formatTime(r.End, "unbounded")
+
+	start, end := r.Start, r.End
+	if len(entries) > 0 {
+		p.First = formatTime(entries[0].time, "")
+		p.Last = formatTime(entries[len(entries)-1].time, "")
+		if start.IsZero() {
+			start = entries[0].time
+		}
+		if end.IsZero() {
+			end = entries[len(entries)-1].time.Add(1)
+		}
+	}
+	p.ChartStart, p.ChartEnd = formatTime(start, ""), formatTime(end, "")
+
+	// counts per level and bucket
+	width := end.Sub(start) / time.Duration(buckets)
+	if end.Sub(start)%time.Duration(buckets) != 0 {
+		width++
+	}
+	if width <= 0 {
+		width = 1
+	}
+	counts := make([][]int, buckets)
+	for i := range counts {
+		counts[i] = make([]int, len(levels))
+	}
+	totals := make([]int, len(levels))
+	messages := map[string]int{}
+	callers := map[string]int{}
+	for _, e := range entries {
+		i := int(e.time.Sub(start) / width)
+		if i >= buckets {
+			i = buckets - 1
+		}
+		l := levelIndex(e.level)
+		counts[i][l]++
+		totals[l]++
+		if isError(e.level) {
+			messages[e.message]++
+			if e.caller != "" {
+				callers[e.caller]++
+			}
+		}
+	}
+
+	for i, level := range levels {
+		if totals[i] > 0 || i < len(levels)-1 {
+			p.Levels = append(p.Levels, levelCount{Name: level.Level.String(), Color: level.Color, Count: totals[i]})
+		}
+	}
+
+	// stacked bars of the levels, the lowest level at the bottom
+	for _, bucket := range counts {
+		n := 0
+		for _, count := range bucket {
+			n += count
+		}
+		if n > p.Max {
+			p.Max = n
+		}
+	}
+	for i, bucket := range counts {
+		y := chartHeight
+		from := start.Add(time.Duration(i) * width)
+		for l, count := range bucket {
+			if count == 0 {
+				continue
+			}
+			h := count * chartHeight / p.Max
+			if h == 0 {
+				h = 1
+			}
+			y -= h
+			p.Bars = append(p.Bars, rect{
+				X:      i*chartBarWidth + 1,
+				Y:      y,
+				Width:  chartBarWidth - 2,
+				Height: h,
+				Color:  levels[l].Color,
+				Title:  formatTime(from, "") + " " + levels[l].Level.String() + ": " + strconv.Itoa(count),
+			})
+		}
+	}
+
+	p.Messages = top(messages, topN)
+	p.Callers = top(callers, topN)
+
+	if len(entries) > maxEntries {
+		p.Omitted = len(entries) - maxEntries
+		entries = entries[:maxEntries]
+	}
+	p.Rows = make([]row, len(entries))
+	for i, e := range entries {
+		var b bytes.Buffer
+		if json.Indent(&b, e.raw, "", "  ") != nil {
+			b.Reset()
+			b.Write(e.raw)
+		}
+		p.Rows[i] = row{
+			Time:    e.text,
+			Level:   levels[levelIndex(e.level)].Level.String(),
+			Source:  r.sources[e.source].Name,
+			Caller:  e.caller,
+			Message: e.message,
+			JSON:    b.String(),
+		}
+	}
+
+	return reportTemplate.Execute(w, &p)
+}
+
+// top returns the n items of the largest counts, ordered by count and name.
+func top(counts map[string]int, n int) []topItem {
+	items := make([]topItem, 0, len(counts))
+	for name, count := range counts {
+		items = append(items, topItem{name, count})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Name < items[j].Name
+	})
+	if len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
+
+func formatTime(t time.Time, zero string) string {
+	if t.IsZero() {
+		return zero
+	}
+	return t.UTC().Format(timeLayout)
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body{font:14px/1.4 -apple-system,"Segoe UI",Helvetica,Arial,sans-serif;margin:24px;color:#222}
+h1{font-size:22px;margin:0 0 12px}
+h2{font-size:17px;margin:28px 0 8px}
+table{border-collapse:collapse}
+th,td{text-align:left;vertical-align:top;padding:3px 8px;border-bottom:1px solid #e4e4e4}
+th{background:#f4f4f4}
+.summary th{background:none;font-weight:600}
+.num{text-align:right}
+.swatch{display:inline-block;width:10px;height:10px;margin-right:4px}
+.chart{width:100%;max-width:1200px;height:auto;border-bottom:1px solid #999}
+.axis{display:flex;justify-content:space-between;max-width:1200px;color:#666;font-size:12px}
+.mono,pre{font-family:SFMono-Regular,Menlo,Consolas,monospace;font-size:12px}
+pre{margin:4px 0;white-space:pre-wrap;word-break:break-all}
+summary{cursor:pointer}
+.error,.fatal,.panic{color:#b52a25}
+.warn{color:#9a6b00}
+#entries{width:100%}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table class="summary">
+<tr><th>Window</th><td>{{.Window}}</td></tr>
+<tr><th>Entries</th><td>{{.Total}}{{if .First}}, from {{.First}} to {{.Last}}{{end}}</td></tr>
+<tr><th>Sources</th><td>{{range $i, $s := .Sources}}{{if $i}}, {{end}}<span class="mono">{{$s.Name}}</span> ({{$s.Entries}}){{end}}</td></tr>
+<tr><th>Levels</th><td>{{range $i, $l := .Levels}}{{if $i}}, {{end}}<span class="swatch" style="background:{{$l.Color}}"></span>{{$l.Name}} {{$l.Count}}{{end}}</td></tr>
+{{- if .Skipped}}
+<tr><th>Skipped</th><td>{{.Skipped}} lines without a JSON entry or a valid time</td></tr>
+{{- end}}
+</table>
+
+<h2>Entries over time</h2>
+<svg class="chart" xmlns="http://www.w3.org/2000/svg" viewBox="0 0 {{.Width}} {{.Height}}" preserveAspectRatio="none" role="img" aria-label="entries per level over time">
+{{- range .Bars}}
+<rect x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}" fill="{{.Color}}"><title>{{.Title}}</title></rect>
+{{- end}}
+</svg>
+<div class="axis"><span>{{.ChartStart}}</span><span>max {{.Max}} per bar</span><span>{{.ChartEnd}}</span></div>
+
+<h2>Top error messages</h2>
+{{- if .Messages}}
+<table>
+<tr><th class="num">Count</th><th>Message</th></tr>
+{{- range .Messages}}
+<tr><td class="num">{{.Count}}</td><td>{{.Name}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No error entries.</p>
+{{- end}}
+
+<h2>Top error callers</h2>
+{{- if .Callers}}
+<table>
+<tr><th class="num">Count</th><th>Caller</th></tr>
+{{- range .Callers}}
+<tr><td class="num">{{.Count}}</td><td class="mono">{{.Name}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No error entries with a caller.</p>
+{{- end}}
+
+<h2>Entries</h2>
+<p>
+<input id="search" type="search" placeholder="Search" size="40">
+<select id="level"><option value="">all levels</option>{{range .Levels}}<option>{{.Name}}</option>{{end}}</select>
+{{- if .Omitted}}
+<span>{{.Omitted}} more entries are not listed.</span>
+{{- end}}
+</p>
+<table id="entries">
+<thead><tr><th>Time</th><th>Level</th><th>Source</th><th>Caller</th><th>Message</th></tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr data-level="{{.Level}}"><td class="mono">{{.Time}}</td><td class="{{.Level}}">{{.Level}}</td><td class="mono">{{.Source}}</td><td class="mono">{{.Caller}}</td><td><details><summary>{{.Message}}</summary><pre>{{.JSON}}</pre></details></td></tr>
+{{- end}}
+</tbody>
+</table>
+<script>
+(function () {
+	var search = document.getElementById("search"), level = document.getElementById("level");
+	var rows = document.querySelectorAll("#entries tbody tr");
+	function filter() {
+		var s = search.value.toLowerCase(), l = level.value;
+		for (var i = 0; i < rows.length; i++) {
+			var row = rows[i];
+			row.hidden = (l !== "" && row.getAttribute("data-level") !== l) || row.textContent.toLowerCase().indexOf(s) < 0;
+		}
+	}
+	search.addEventListener("input", filter);
+	level.addEventListener("change", filter);
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/fabricatorsltd/logstack"
+)
+
+const api = `{"segment":"header","time":"2019-07-10T05:36:10.000Z","host":"shire","pid":1234}
+{"time":"2019-07-10T05:35:00.000Z","level":"info","message":"before window"}
+{"time":"2019-07-10T05:36:00.000Z","level":"info","caller":"api.go:10","message":"request"}
+{"time":"2019-07-10T05:37:00.000Z","level":"error","caller":"db.go:42","message":"connection refused <db>"}
+not a json line
+{"time":"2019-07-10T05:38:30.000Z","level":"error","caller":"db.go:42","message":"connection refused <db>"}
+{"time":"2019-07-10T05:45:00.000Z","level":"info","message":"after window"}
+{"segment":"footer","time":"2019-07-10T05:37:10.000Z","entries":6,"checksum":"crc32c:00000000"}
+`
+
+const worker = `{"ts":1562737080.5,"level":"warn","caller":"worker.go:7","msg":"retrying","attempt":2}
+{"ts":1562737110,"level":"fatal","caller":"worker.go:9","msg":"giving up"}
+`
+
+func newTestReport() *Report {
+	r := &Report{
+		Title:   "DB outage",
+		Start:   time.Date(2019, 7, 10, 5, 36, 0, 0, time.UTC),
+		End:     time.Date(2019, 7, 10, 5, 40, 0, 0, time.UTC),
+		Buckets: 4,
+		Aliases: log.AutoFieldAliases,
+	}
+	_ = r.Add("api.log", strings.NewReader(api))
+	_ = r.Add("worker.log", strings.NewReader(worker))
+	return r
+}
+
+func TestReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestReport().WriteHTML(&buf); err != nil {
+		t.Fatalf("report WriteHTML error: %+v", err)
+	}
+	html := buf.String()
+
+	for _, s := range []string{
+		"<title>DB outage</title>",
+		"2019-07-10T05:36:00.000Z to 2019-07-10T05:40:00.000Z",
+		`<span class="mono">api.log</span> (3), <span class="mono">worker.log</span> (2)`,
+		"info 1, ",
+		"</span>error 2, ",
+		"<tr><th>Skipped</th><td>1 lines",
+		`viewBox="0 0 64 160"`,
+		`<rect x="17" y="107" width="14" height="53" fill="#d9534f"><title>2019-07-10T05:37:00.000Z error: 1</title></rect>`,
+		`<tr><td class="num">2</td><td>connection refused &lt;db&gt;</td></tr>`,
+		`<tr><td class="num">1</td><td>giving up</td></tr>`,
+		`<tr><td class="num">2</td><td class="mono">db.go:42</td></tr>`,
+		`<tr data-level="warn"><td class="mono">2019-07-10T05:38:00.5Z</td>`,
+		"&#34;attempt&#34;: 2",
+		"<script>",
+	} {
+		if !strings.Contains(html, s) {
+			t.Errorf("report does not contain %q", s)
+		}
+	}
+	for _, s := range []string{"before window", "after window", "src=", "<link", "@import"} {
+		if strings.Contains(html, s) {
+			t.Errorf("report contains %q", s)
+		}
+	}
+
+	// the entries are listed by time, the entries of other sources are interleaved
+	if i, j := strings.Index(html, "retrying"), strings.Index(html, "giving up</summary>"); i < 0 || j < i ||
+		strings.Index(html, `<summary>request</summary>`) > i {
+		t.Errorf("report entries are not ordered by time")
+	}
+
+	var again bytes.Buffer
+	_ = newTestReport().WriteHTML(&again)
+	if again.String() != html {
+		t.Errorf("report output is not deterministic")
+	}
+}
+
+func TestReportMaxEntries(t *testing.T) {
+	r := &Report{MaxEntries: 2}
+	_ = r.Add("api.log", strings.NewReader(api))
+
+	var buf bytes.Buffer
+	if err := r.WriteHTML(&buf); err != nil {
+		t.Fatalf("report WriteHTML error: %+v", err)
+	}
+	html := buf.String()
+	if n := strings.Count(html, "<tr data-level="); n != 2 {
+		t.Errorf("report rows %d not equal to 2", n)
+	}
+	if !strings.Contains(html, "3 more entries are not listed.") || !strings.Contains(html, "unbounded to unbounded") {
+		t.Errorf("report summary not correct: %s", html)
+	}
+
+	if err := (&Report{}).WriteHTML(&buf); err == nil {
+		t.Errorf("report without sources should return an error")
+	}
+}
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"sync"
+)
+
+// HashSampler samples entries by hashing a key, e.g. a trace id, a request XID or a user id,
+// so that all entries sharing the key are kept or dropped together.
+//
+// The hash is stable across processes, so every service sampling on the same key
+// makes the same decision. A key kept at a lower rate is also kept at any higher rate.
+//
+// To sample a logger by key, set Logger.Sampler and Logger.SampleKey:
+//
+//	logger := log.Logger{Sampler: &log.HashSampler{Rate: 0.1, KeepErrors: true}}
+//	reqLogger := logger
+//	reqLogger.SampleKey = traceID
+//	reqLogger.Info().Msg("sampled by trace id")
+type HashSampler struct {
+	// Rate specifies the fraction of keys to keep, in the range [0, 1].
+	Rate float64
+
+	// LevelRates optionally overrides Rate for specific levels.
+	LevelRates map[Level]float64
+
+	// KeepErrors determines if entries at error level or above are always kept.
+	KeepErrors bool
+
+	once       sync.Once
+	thresholds [noLevel + 1]uint64
+}
+
+// Sample reports whether an entry of the level with the key should be kept.
+// Entries with an empty key are always kept.
+func (s *HashSampler) Sample(level Level, key string) bool {
+	if key == "" {
+		return true
+	}
+	if level > noLevel {
+		level = noLevel
+	}
+
+	s.once.Do(s.init)
+
+	threshold := s.thresholds[level]
+	switch threshold {
+	case 0:
+		return false
+	case ^uint64(0):
+		return true
+	}
+
+	return hashKey(key) < threshold
+}
+
+func (s *HashSampler) init() {
+	for level := range s.thresholds {
+		rate, ok := s.LevelRates[Level(level)]
+		if !ok {
+			rate = s.Rate
+		}
+		if s.KeepErrors && Level(level) >= ErrorLevel && Level(level) != noLevel {
+			rate = 1
+		}
+		const scale = 1 << 64
+		switch {
+		case rate <= 0:
+			s.thresholds[level] = 0
+		case rate*scale >= scale:
+			s.thresholds[level] = ^uint64(0)
+		default:
+			s.thresholds[level] = uint64(rate * scale)
+		}
+	}
+}
+
+// hashKey returns a well distributed 64-bit FNV-1a hash of the key.
+func hashKey(key string) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= prime64
+	}
+	// murmur3 finalizer
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
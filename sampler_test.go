@@ -0,0 +1,99 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHashSamplerConsistent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{
+		Level:   TraceLevel,
+		Writer:  IOWriter{&buf},
+		Sampler: &HashSampler{Rate: 0.3},
+	}
+
+	for i := 0; i < 20; i++ {
+		l := logger
+		l.SampleKey = "trace-" + strconv.Itoa(i)
+
+		buf.Reset()
+		l.Info().Msg("a")
+		l.Warn().Msg("b")
+		l.Error().Msg("c")
+
+		if n := strings.Count(buf.String(), "\n"); n != 0 && n != 3 {
+			t.Fatalf("hash sampler split key %s: kept %d of 3 entries", l.SampleKey, n)
+		}
+	}
+}
+
+func TestHashSamplerRate(t *testing.T) {
+	s := &HashSampler{Rate: 0.3}
+
+	var kept int
+	for i := 0; i < 10000; i++ {
+		if s.Sample(InfoLevel, "trace-"+strconv.Itoa(i)) {
+			kept++
+		}
+	}
+
+	if kept < 2700 || kept > 3300 {
+		t.Errorf("hash sampler rate not correct, kept %d of 10000 keys", kept)
+	}
+}
+
+func TestHashSamplerLevelRates(t *testing.T) {
+	s := &HashSampler{
+		Rate:       0.5,
+		LevelRates: map[Level]float64{DebugLevel: 0, InfoLevel: 1},
+		KeepErrors: true,
+	}
+
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		if s.Sample(DebugLevel, key) {
+			t.Errorf("hash sampler should drop debug level")
+		}
+		if !s.Sample(InfoLevel, key) {
+			t.Errorf("hash sampler should keep info level")
+		}
+		if !s.Sample(ErrorLevel, key) || !s.Sample(PanicLevel, key) {
+			t.Errorf("hash sampler should keep errors")
+		}
+		if s.Sample(WarnLevel, key) != s.Sample(TraceLevel, key) {
+			t.Errorf("hash sampler should decide same rate levels by key")
+		}
+	}
+
+	if !s.Sample(DebugLevel, "") {
+		t.Errorf("hash sampler should keep empty key")
+	}
+}
+
+func TestHashSamplerSubset(t *testing.T) {
+	low, high := &HashSampler{Rate: 0.1}, &HashSampler{Rate: 0.5}
+	for i := 0; i < 1000; i++ {
+		key := NewXID().String()
+		if low.Sample(InfoLevel, key) && !high.Sample(InfoLevel, key) {
+			t.Errorf("hash sampler key %s kept at lower rate but dropped at higher rate", key)
+		}
+	}
+}
+
+func BenchmarkHashSampler(b *testing.B) {
+	logger := Logger{
+		Writer:    IOWriter{io.Discard},
+		Sampler:   &HashSampler{Rate: 0.01},
+		SampleKey: NewXID().String(),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info().Str("foo", "bar").Msg("hello world")
+	}
+}
@@ -0,0 +1,380 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Scrubber removes entries or redacts fields of existing JSON log files, e.g. to purge
+// the identifiers of a customer requesting deletion.
+//
+//	s := &log.Scrubber{
+//		Match: func(key, value string) bool { return key == "user_id" && value == "42" },
+//		Audit: &log.Logger{Writer: &log.FileWriter{Filename: "scrub-audit.log"}},
+//	}
+//	stats, err := s.ScrubFiles("/var/log/app.log")
+//
+// Lines which are not JSON objects, e.g. the FileWriter.Header, and the segment markers are kept verbatim.
+type Scrubber struct {
+	// Match reports whether a top-level field matches, the value of a string field is unquoted
+	// and the values of other fields are the JSON text.
+	Match func(key, value string) bool
+
+	// Remove determines if the matching entries are removed, the matching fields are redacted otherwise.
+	Remove bool
+
+	// Redaction specifies the replacement string of the redacted fields, the default is "[REDACTED]".
+	Redaction string
+
+	// Audit specifies an optional logger of the audit summary, one entry for every changed file and a total.
+	// The summary contains the counts of changes only, never the matching values.
+	Audit *Logger
+}
+
+// ScrubStats is the summary of the changes to a log file.
+type ScrubStats struct {
+	// File is the name of the file.
+	File string
+
+	// Entries is the number of entries read.
+	Entries int
+
+	// Removed is the number of entries removed.
+	Removed int
+
+	// Redacted is the number of fields redacted.
+	Redacted int
+}
+
+// Changed reports whether the file is changed.
+func (st ScrubStats) Changed() bool {
+	return st.Removed != 0 || st.Redacted != 0
+}
+
+// Scrub copies the log lines of src to dst, removing or redacting the matching entries.
+func (s *Scrubber) Scrub(dst io.Writer, src io.Reader) (stats ScrubStats, err error) {
+	if s.Match == nil {
+		return stats, errors.New("log: Scrubber.Match is nil")
+	}
+	redaction, _ := json.Marshal(s.Redaction)
+	if s.Redaction == "" {
+		redaction = []byte(`"[REDACTED]"`)
+	}
+
+	br := bufio.NewReaderSize(src, 64*1024)
+	bw := bufio.NewWriterSize(dst, 64*1024)
+	var out []byte
+	for {
+		line, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// a long line
+			buf := append([]byte(nil), line...)
+			for err == bufio.ErrBufferFull {
+				line, err = br.ReadSlice('\n')
+				buf = append(buf, line...)
+			}
+			line = buf
+		}
+		if len(line) > 0 {
+			if members, ok := jsonMembers(line); ok && !IsSegmentMarker(line) {
+				stats.Entries++
+				out = out[:0]
+				removed, redacted := false, 0
+				last := 0
+				for _, m := range members {
+					if !s.Match(m.key, m.value(line)) {
+						continue
+					}
+					if s.Remove {
+						removed = true
+						break
+					}
+					out = append(out, line[last:m.start]...)
+					out = append(out, redaction...)
+					last = m.end
+					redacted++
+				}
+				switch {
+				case removed:
+					stats.Removed++
+					line = nil
+				case redacted != 0:
+					stats.Redacted += redacted
+					line = append(out, line[last:]...)
+				}
+			}
+			if _, err1 := bw.Write(line); err1 != nil {
+				return stats, err1
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+	}
+	return stats, bw.Flush()
+}
+
+// ScrubFile scrubs a log file in place, the file is decompressed and compressed again if its name ends with .gz.
+// The file is replaced atomically, keeping its mode and modification time, and is left untouched if nothing matches.
+// A truncated or corrupt gzip stream is an error, and the file is left untouched too.
+func (s *Scrubber) ScrubFile(name string) (stats ScrubStats, err error) {
+	stats.File = name
+
+	file, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	st, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".scrub-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if tmp != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	var src io.Reader = file
+	var dst io.Writer = tmp
+	var gw *gzip.Writer
+	if strings.HasSuffix(name, ".gz") {
+		var gr *gzip.Reader
+		switch gr, err = gzip.NewReader(file); err {
+		case nil:
+		case io.EOF:
+			// an empty stream
+			err = nil
+			return
+		default:
+			return
+		}
+		defer gr.Close()
+		gw, _ = gzip.NewWriterLevel(tmp, gzip.BestCompression)
+		gw.Header = gr.Header
+		// a truncated or corrupt stream is an error, rewriting it would lose the entries after the damage
+		src, dst = gr, gw
+	}
+
+	var n ScrubStats
+	if n, err = s.Scrub(dst, src); err != nil {
+		return
+	}
+	stats.Entries, stats.Removed, stats.Redacted = n.Entries, n.Removed, n.Redacted
+	if !stats.Changed() {
+		return
+	}
+
+	if gw != nil {
+		if err = gw.Close(); err != nil {
+			return
+		}
+	}
+	if err = tmp.Chmod(st.Mode().Perm()); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	if err = os.Chtimes(tmp.Name(), st.ModTime(), st.ModTime()); err != nil {
+		return
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return
+	}
+	tmp = nil
+	return
+}
+
+// ScrubFiles scrubs the rotation set of a FileWriter filename, the current log file and
+// all backups including the compressed ones. It returns the stats of every file in the set.
+//
+// The current log file of a running FileWriter should be rotated by FileWriter.Rotate first,
+// otherwise the entries written during the scrubbing are lost. The active gzip stream of a
+// FileWriter with Gzip, linked by filename.gz, is skipped, it is scrubbed after the rotation.
+func (s *Scrubber) ScrubFiles(filename string) ([]ScrubStats, error) {
+	names, err := rotationFiles(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var total ScrubStats
+	var stats []ScrubStats
+	for _, name := range names {
+		st, err := s.ScrubFile(name)
+		if err != nil {
+			return stats, err
+		}
+		stats = append(stats, st)
+		total.Entries += st.Entries
+		total.Removed += st.Removed
+		total.Redacted += st.Redacted
+		if s.Audit != nil && st.Changed() {
+			s.Audit.Info().Str("file", st.File).Int("entries", st.Entries).Int("removed", st.Removed).Int("redacted", st.Redacted).Msg("log file scrubbed")
+		}
+	}
+	if s.Audit != nil {
+		s.Audit.Info().Str("filename", filename).Int("files", len(stats)).Int("entries", total.Entries).Int("removed", total.Removed).Int("redacted", total.Redacted).Msg("log files scrubbed")
+	}
+	return stats, nil
+}
+
+// rotationFiles returns the regular files of the rotation set of a FileWriter filename, sorted by name,
+// except the active gzip stream.
+func rotationFiles(filename string) ([]string, error) {
+	dir := filepath.Dir(filename)
+	infos, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	base, ext := filepath.Base(filename), filepath.Ext(filename)
+	prefix, extgz := base[:len(base)-len(ext)]+".", ext+".gz"
+	// the active gzip stream of a FileWriter is unterminated and still written
+	active, _ := os.Readlink(filename + ".gz")
+
+	var names []string
+	for _, info := range infos {
+		name := info.Name()
+		if !info.Type().IsRegular() {
+			// the symlink to the current log file
+			continue
+		}
+		if name == filepath.Base(active) {
+			continue
+		}
+		if name == base || (strings.HasPrefix(name, prefix) && (strings.HasSuffix(name, ext) || strings.HasSuffix(name, extgz))) {
+			names = append(names, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// jsonMember is a top-level member of a JSON object, with the offsets of its value.
+type jsonMember struct {
+	key        string
+	start, end int
+}
+
+// value returns the unquoted string or the JSON text of the member value.
+func (m jsonMember) value(line []byte) string {
+	v := line[m.start:m.end]
+	if v[0] != '"' {
+		return string(v)
+	}
+	if bytes.IndexByte(v, '\\') < 0 {
+		return string(v[1 : len(v)-1])
+	}
+	var s string
+	_ = json.Unmarshal(v, &s)
+	return s
+}
+
+// jsonMembers scans the top-level members of a JSON object line, it returns false if the line is not an object.
+func jsonMembers(line []byte) ([]jsonMember, bool) {
+	i := skipSpace(line, 0)
+	if i >= len(line) || line[i] != '{' {
+		return nil, false
+	}
+	var members []jsonMember
+	for i = skipSpace(line, i+1); i < len(line) && line[i] != '}'; {
+		if line[i] != '"' {
+			return nil, false
+		}
+		end := skipString(line, i)
+		if end < 0 {
+			return nil, false
+		}
+		key := jsonMember{start: i, end: end}.value(line)
+		if i = skipSpace(line, end); i >= len(line) || line[i] != ':' {
+			return nil, false
+		}
+		start := skipSpace(line, i+1)
+		if end = skipValue(line, start); end < 0 {
+			return nil, false
+		}
+		members = append(members, jsonMember{key, start, end})
+		if i = skipSpace(line, end); i < len(line) && line[i] == ',' {
+			i = skipSpace(line, i+1)
+		}
+	}
+	return members, i < len(line)
+}
+
+func skipSpace(b []byte, i int) int {
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t' || b[i] == '\r' || b[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// skipString returns the offset after the string starting at i, or -1.
+func skipString(b []byte, i int) int {
+	for i++; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// skipValue returns the offset after the value starting at i, or -1.
+func skipValue(b []byte, i int) int {
+	if i >= len(b) {
+		return -1
+	}
+	switch b[i] {
+	case '"':
+		return skipString(b, i)
+	case '{', '[':
+		depth := 0
+		for ; i < len(b); i++ {
+			switch b[i] {
+			case '"':
+				if i = skipString(b, i); i < 0 {
+					return -1
+				}
+				i--
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return -1
+	}
+	start := i
+	for i < len(b) && b[i] != ',' && b[i] != '}' && b[i] != ']' && b[i] != ' ' && b[i] != '\n' && b[i] != '\r' && b[i] != '\t' {
+		i++
+	}
+	if i == start {
+		return -1
+	}
+	return i
+}
@@ -0,0 +1,267 @@
+package log
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScrub(t *testing.T) {
+	input := `# header of log file
+{"time":"2019-07-10T05:35:54.277Z","level":"info","user_id":"42","email":"a@b.c","message":"login"}
+{"time":"2019-07-10T05:35:55.277Z","level":"info","user_id":"43","message":"login"}
+{"level":"warn","user":{"id":42},"note":"say \"42\"", "user_id" : 42 ,"message":"nested"}
+{"level":"error","user_id":"42","message":"escaped"}
+{"broken":
+`
+	match := func(key, value string) bool {
+		return (key == "user_id" && value == "42") || key == "email" || (key == "user" && value == `{"id":42}`)
+	}
+
+	var buf bytes.Buffer
+	stats, err := (&Scrubber{Match: match}).Scrub(&buf, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("scrub error: %+v", err)
+	}
+	want := `# header of log file
+{"time":"2019-07-10T05:35:54.277Z","level":"info","user_id":"[REDACTED]","email":"[REDACTED]","message":"login"}
+{"time":"2019-07-10T05:35:55.277Z","level":"info","user_id":"43","message":"login"}
+{"level":"warn","user":"[REDACTED]","note":"say \"42\"", "user_id" : "[REDACTED]" ,"message":"nested"}
+{"level":"error","user_id":"[REDACTED]","message":"escaped"}
+{"broken":
+`
+	if buf.String() != want {
+		t.Errorf("scrub redacted output not correct:\n%s", buf.String())
+	}
+	if stats != (ScrubStats{Entries: 4, Redacted: 5}) {
+		t.Errorf("scrub redacted stats not correct: %+v", stats)
+	}
+
+	buf.Reset()
+	stats, err = (&Scrubber{Match: match, Remove: true}).Scrub(&buf, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("scrub error: %+v", err)
+	}
+	want = `# header of log file
+{"time":"2019-07-10T05:35:55.277Z","level":"info","user_id":"43","message":"login"}
+{"broken":
+`
+	if buf.String() != want {
+		t.Errorf("scrub removed output not correct:\n%s", buf.String())
+	}
+	if stats != (ScrubStats{Entries: 4, Removed: 3}) {
+		t.Errorf("scrub removed stats not correct: %+v", stats)
+	}
+
+	if _, err = (&Scrubber{}).Scrub(&buf, strings.NewReader(input)); err == nil {
+		t.Errorf("scrub without Match should return an error")
+	}
+}
+
+func TestScrubFiles(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	current := filepath.Join(dir, "app.2019-07-11T05-35-54.log")
+	backup := filepath.Join(dir, "app.2019-07-10T05-35-54.log.gz")
+	unrelated := filepath.Join(dir, "other.log")
+	mtime := time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC)
+
+	entries := `{"level":"info","user_id":"u-7781","message":"a"}` + "\n" + `{"level":"info","user_id":"43","message":"b"}` + "\n"
+	for _, name := range []string{current, unrelated} {
+		if err := os.WriteFile(name, []byte(entries), 0600); err != nil {
+			t.Fatalf("write log file error: %+v", err)
+		}
+	}
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Name = "app.2019-07-10T05-35-54.log"
+	_, _ = gw.Write([]byte(entries))
+	gw.Close()
+	if err := os.WriteFile(backup, buf.Bytes(), 0640); err != nil {
+		t.Fatalf("write gzip file error: %+v", err)
+	}
+	for _, name := range []string{current, backup} {
+		if err := os.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatalf("chtimes error: %+v", err)
+		}
+	}
+	if err := os.Symlink(filepath.Base(current), filename); err != nil {
+		t.Fatalf("symlink error: %+v", err)
+	}
+
+	var audit bytes.Buffer
+	s := &Scrubber{
+		Match:  func(key, value string) bool { return key == "user_id" && value == "u-7781" },
+		Remove: true,
+		Audit:  &Logger{Level: InfoLevel, Writer: IOWriter{&audit}},
+	}
+	stats, err := s.ScrubFiles(filename)
+	if err != nil {
+		t.Fatalf("scrub files error: %+v", err)
+	}
+	if len(stats) != 2 || stats[0].File != backup || stats[1].File != current || stats[0].Removed != 1 || stats[1].Removed != 1 {
+		t.Fatalf("scrub files stats not correct: %+v", stats)
+	}
+
+	want := `{"level":"info","user_id":"43","message":"b"}` + "\n"
+	if b, _ := os.ReadFile(current); string(b) != want {
+		t.Errorf("scrub current file not correct: %s", b)
+	}
+	if b, _ := os.ReadFile(unrelated); string(b) != entries {
+		t.Errorf("scrub should not change unrelated file: %s", b)
+	}
+	if target, _ := os.Readlink(filename); target != filepath.Base(current) {
+		t.Errorf("scrub should keep the symlink: %s", target)
+	}
+
+	file, err := os.Open(backup)
+	if err != nil {
+		t.Fatalf("open gzip file error: %+v", err)
+	}
+	defer file.Close()
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip reader error: %+v", err)
+	}
+	if b, _ := io.ReadAll(gr); string(b) != want || gr.Name != "app.2019-07-10T05-35-54.log" {
+		t.Errorf("scrub gzip file not correct: %q %s", gr.Name, b)
+	}
+
+	for name, mode := range map[string]os.FileMode{current: 0600, backup: 0640} {
+		st, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("stat error: %+v", err)
+		}
+		if st.Mode().Perm() != mode || !st.ModTime().Equal(mtime) {
+			t.Errorf("scrub should keep mode and mtime of %s: %v %v", name, st.Mode(), st.ModTime())
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], `"files":2,"entries":4,"removed":2,"redacted":0`) {
+		t.Errorf("scrub audit not correct: %s", audit.String())
+	}
+	if strings.Contains(audit.String(), "u-7781") {
+		t.Errorf("scrub audit should not contain the matching values: %s", audit.String())
+	}
+
+	// a second pass changes nothing
+	audit.Reset()
+	if stats, err = s.ScrubFiles(filename); err != nil || stats[0].Changed() || stats[1].Changed() {
+		t.Errorf("scrub files second pass not correct: %+v %+v", stats, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 4 {
+		t.Errorf("scrub should not leave temporary files: %v", entries)
+	}
+}
+
+func TestScrubFilesGzipActive(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+
+	w := &FileWriter{Filename: filename, Gzip: true, FlushInterval: 10 * time.Millisecond, Cleaner: func(string, int, []os.FileInfo) {}}
+	defer w.Close()
+	_, _ = wlprintf(w, InfoLevel, `{"user_id":"u-7781","message":"a"}`+"\n")
+	if err := w.Rotate(); err != nil {
+		t.Fatalf("file writer rotate error: %+v", err)
+	}
+	_, _ = wlprintf(w, InfoLevel, `{"user_id":"u-7781","message":"b"}`+"\n")
+	time.Sleep(100 * time.Millisecond)
+
+	active, err := os.Readlink(filename + ".gz")
+	if err != nil {
+		t.Fatalf("readlink error: %+v", err)
+	}
+
+	var audit bytes.Buffer
+	s := &Scrubber{
+		Match:  func(key, value string) bool { return key == "user_id" && value == "u-7781" },
+		Remove: true,
+		Audit:  &Logger{Level: InfoLevel, Writer: IOWriter{&audit}},
+	}
+	stats, err := s.ScrubFiles(filename)
+	if err != nil {
+		t.Fatalf("scrub files error: %+v", err)
+	}
+	if len(stats) != 1 || filepath.Base(stats[0].File) == active || stats[0].Removed != 1 {
+		t.Errorf("scrub files should skip the active gzip stream %s: %+v", active, stats)
+	}
+	if !strings.Contains(audit.String(), `"files":1,"entries":1,"removed":1`) {
+		t.Errorf("scrub audit not correct: %s", audit.String())
+	}
+
+	// a truncated stream is an error and is left untouched
+	b, err := os.ReadFile(filepath.Join(dir, active))
+	if err != nil {
+		t.Fatalf("read active file error: %+v", err)
+	}
+	truncated := filepath.Join(dir, "app.crashed.log.gz")
+	if err := os.WriteFile(truncated, b, 0644); err != nil {
+		t.Fatalf("write truncated file error: %+v", err)
+	}
+	if _, err := s.ScrubFile(truncated); err == nil {
+		t.Errorf("scrub truncated gzip file should error")
+	}
+	if data, _ := os.ReadFile(truncated); !bytes.Equal(data, b) {
+		t.Errorf("scrub truncated gzip file should leave the file untouched")
+	}
+}
+
+func TestScrubFileGzipCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(`{"user_id":"u-7781","message":"a"}` + "\n"))
+	_ = gz.Flush()
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(gz, `{"n":%d,"message":"%x"}`+"\n", i, i*i*7919)
+	}
+	gz.Close()
+
+	// damage the deflate data after the matching entry
+	var b []byte
+	for off := len(buf.Bytes()) / 2; b == nil && off < len(buf.Bytes())-8; off++ {
+		b = append([]byte(nil), buf.Bytes()...)
+		for i := off; i < off+8; i++ {
+			b[i] ^= 0xff
+		}
+		gr, _ := gzip.NewReader(bytes.NewReader(b))
+		if _, err := io.Copy(io.Discard, gr); !errors.As(err, new(flate.CorruptInputError)) {
+			b = nil
+		}
+	}
+	if b == nil {
+		t.Fatalf("no corrupt deflate stream")
+	}
+	filename := filepath.Join(t.TempDir(), "app.2019-07-10T05-35-54.log.gz")
+	if err := os.WriteFile(filename, b, 0644); err != nil {
+		t.Fatalf("write corrupt file error: %+v", err)
+	}
+
+	s := &Scrubber{
+		Match:  func(key, value string) bool { return key == "user_id" && value == "u-7781" },
+		Remove: true,
+	}
+	if _, err := s.ScrubFile(filename); err == nil {
+		t.Errorf("scrub corrupt gzip file should error")
+	}
+	if data, _ := os.ReadFile(filename); !bytes.Equal(data, b) {
+		t.Errorf("scrub corrupt gzip file should leave the file untouched")
+	}
+
+	// only a truncated stream ends silently in NewGzipReader
+	r, err := NewGzipReader(bytes.NewReader(b))
+	if err == nil {
+		_, err = io.ReadAll(r)
+	}
+	if err == nil {
+		t.Errorf("gzip reader of a corrupt stream should error")
+	}
+}
@@ -0,0 +1,155 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"runtime/debug"
+	"time"
+)
+
+// Segment enables the self-describing segment format of FileWriter, every log file starts with
+// a header entry and ends with a footer entry on rotation or close. Every segment is a new file.
+//
+// The header records the host, pid, program version, logger settings and the previous segment:
+//
+//	{"segment":"header","time":"2019-07-10T05:35:54.277Z","host":"shire","pid":1234,"version":"v1.2.0","logger":{"level":"info","caller":1},"previous":"app.2019-07-10T04-35-54.log"}
+//
+// The footer records the number and the write times of the first and last entries, and the
+// CRC-32C checksum of the segment before the footer, see VerifySegment:
+//
+//	{"segment":"footer","time":"2019-07-10T06:35:54.277Z","entries":4242,"first":"2019-07-10T05:35:54.278Z","last":"2019-07-10T06:35:54.101Z","checksum":"crc32c:0a1b2c3d"}
+//
+// The ConsoleWriter, Scrubber and the readers of the subpackages recognise both by IsSegmentMarker.
+// A scrubbed segment does not match its checksum.
+type Segment struct {
+	// Version specifies the program version, the default is the module version of the main package.
+	Version string
+
+	// Logger specifies the optional logger whose settings are recorded in the header.
+	Logger *Logger
+}
+
+var (
+	segmentHeader = []byte(`{"segment":"header"`)
+	segmentFooter = []byte(`{"segment":"footer"`)
+	castagnoli    = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// IsSegmentMarker reports whether a log line is the header or footer entry of a segment.
+func IsSegmentMarker(line []byte) bool {
+	return bytes.HasPrefix(line, segmentHeader) || bytes.HasPrefix(line, segmentFooter)
+}
+
+// segmentState is the running footer of the current segment.
+type segmentState struct {
+	crc     uint32
+	entries int64
+	first   time.Time
+	last    time.Time
+}
+
+// update records the bytes written to the segment, and the lines as entries written at now if it is not zero.
+func (s *segmentState) update(b []byte, now time.Time) {
+	s.crc = crc32.Update(s.crc, castagnoli, b)
+	if now.IsZero() {
+		return
+	}
+	if n := int64(bytes.Count(b, []byte{'\n'})); n != 0 {
+		if s.entries == 0 {
+			s.first = now
+		}
+		s.entries += n
+		s.last = now
+	}
+}
+
+func (s *Segment) version() string {
+	if s.Version != "" {
+		return s.Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return ""
+}
+
+func (s *Segment) header(previous string) []byte {
+	e := Entry{buf: append(make([]byte, 0, 256), segmentHeader...)}
+	e.Time("time", timeNow())
+	e.Str("host", hostname)
+	e.Int("pid", pid)
+	if version := s.version(); version != "" {
+		e.Str("version", version)
+	}
+	if l := s.Logger; l != nil {
+		ctx := NewContext(nil).Str("level", l.Level.String()).Int("caller", l.Caller)
+		if l.TimeField != "" {
+			ctx.Str("time_field", l.TimeField)
+		}
+		switch l.TimeFormat {
+		case "":
+		case TimeFormatUnix:
+			ctx.Str("time_format", "unix")
+		case TimeFormatUnixMs:
+			ctx.Str("time_format", "unix_ms")
+		case TimeFormatUnixWithMs:
+			ctx.Str("time_format", "unix_with_ms")
+		default:
+			ctx.Str("time_format", l.TimeFormat)
+		}
+		if l.SampleKey != "" {
+			ctx.Str("sample_key", l.SampleKey)
+		}
+		e.Dict("logger", ctx.Value())
+	}
+	if previous != "" {
+		e.Str("previous", previous)
+	}
+	return append(e.buf, '}', '\n')
+}
+
+func (s *segmentState) footer() []byte {
+	e := Entry{buf: append(make([]byte, 0, 256), segmentFooter...)}
+	e.Time("time", timeNow())
+	e.Int64("entries", s.entries)
+	if s.entries != 0 {
+		e.Time("first", s.first)
+		e.Time("last", s.last)
+	}
+	e.Str("checksum", fmt.Sprintf("crc32c:%08x", s.crc))
+	return append(e.buf, '}', '\n')
+}
+
+// VerifySegment reads a segment written by FileWriter with Segment, and returns an error if
+// it has no footer, or the segment before the footer does not match the checksum of the footer.
+func VerifySegment(r io.Reader) error {
+	crc := uint32(0)
+	br := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, err := br.ReadBytes('\n')
+		if bytes.HasPrefix(line, segmentFooter) {
+			var footer struct {
+				Checksum string `json:"checksum"`
+			}
+			if err := json.Unmarshal(line, &footer); err != nil {
+				return errors.New("log: invalid segment footer: " + err.Error())
+			}
+			if checksum := fmt.Sprintf("crc32c:%08x", crc); footer.Checksum != checksum {
+				return errors.New("log: segment checksum " + checksum + " does not match the footer " + footer.Checksum)
+			}
+			return nil
+		}
+		crc = crc32.Update(crc, castagnoli, line)
+		if err == io.EOF {
+			return errors.New("log: segment footer not found")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
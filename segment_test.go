@@ -0,0 +1,115 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWriterSegment(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+
+	now := time.Date(2019, 7, 10, 5, 35, 54, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	origHost, origPid := hostname, pid
+	hostname, pid = "shire", 1234
+	defer func() { hostname, pid = origHost, origPid }()
+
+	segment := &Segment{
+		Version: "v1.2.0",
+		Logger:  &Logger{Level: InfoLevel, Caller: 1, TimeFormat: TimeFormatUnixMs},
+	}
+	w := &FileWriter{Filename: filename, Segment: segment, Cleaner: func(string, int, []os.FileInfo) {}}
+	for i := 0; i < 3; i++ {
+		_, _ = wlprintf(w, InfoLevel, `{"level":"info","n":%d}`+"\n", i)
+		now = now.Add(time.Second)
+	}
+	_ = w.Rotate()
+	_, _ = wlprintf(w, InfoLevel, `{"level":"info","n":3}`+"\n")
+	w.Close()
+
+	// a new writer continues after the previous segment, which the symlink refers to
+	time.Sleep(100 * time.Millisecond)
+	w = &FileWriter{Filename: filename, Segment: &Segment{Version: "v1.2.0"}}
+	_, _ = wlprintf(w, InfoLevel, `{"level":"info","n":4}`+"\n")
+	w.Close()
+
+	read := func(name string) []map[string]interface{} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("read segment error: %+v", err)
+		}
+		if err := VerifySegment(bytes.NewReader(data)); err != nil {
+			t.Errorf("verify segment %s error: %+v", name, err)
+		}
+		var lines []map[string]interface{}
+		for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+			var m map[string]interface{}
+			if err := json.Unmarshal(line, &m); err != nil {
+				t.Fatalf("segment %s line %s is not JSON: %+v", name, line, err)
+			}
+			lines = append(lines, m)
+		}
+		return lines
+	}
+	equal := func(m map[string]interface{}, expected string) {
+		t.Helper()
+		delete(m, "checksum")
+		if b, _ := json.Marshal(m); string(b) != expected {
+			t.Errorf("segment marker %s, expected %s", b, expected)
+		}
+	}
+
+	first := read("app.2019-07-10T05-35-54.log")
+	if len(first) != 5 {
+		t.Fatalf("first segment lines not correct: %+v", first)
+	}
+	equal(first[0], `{"host":"shire","logger":{"caller":1,"level":"info","time_format":"unix_ms"},"pid":1234,"segment":"header","time":"2019-07-10T05:35:54Z","version":"v1.2.0"}`)
+	equal(first[4], `{"entries":3,"first":"2019-07-10T05:35:54Z","last":"2019-07-10T05:35:56Z","segment":"footer","time":"2019-07-10T05:35:57Z"}`)
+
+	second := read("app.2019-07-10T05-35-57.log")
+	if len(second) != 3 {
+		t.Fatalf("second segment lines not correct: %+v", second)
+	}
+	equal(second[0], `{"host":"shire","logger":{"caller":1,"level":"info","time_format":"unix_ms"},"pid":1234,"previous":"app.2019-07-10T05-35-54.log","segment":"header","time":"2019-07-10T05:35:57Z","version":"v1.2.0"}`)
+	equal(second[2], `{"entries":1,"first":"2019-07-10T05:35:57Z","last":"2019-07-10T05:35:57Z","segment":"footer","time":"2019-07-10T05:35:57Z"}`)
+
+	third := read("app.2019-07-10T05-35-57.1.log")
+	if len(third) != 3 || third[0]["previous"] != "app.2019-07-10T05-35-57.log" || third[1]["n"] != 4.0 {
+		t.Errorf("third segment lines not correct: %+v", third)
+	}
+
+	// a changed segment does not match its checksum
+	data, _ := os.ReadFile(filepath.Join(dir, "app.2019-07-10T05-35-54.log"))
+	if err := VerifySegment(bytes.NewReader(bytes.Replace(data, []byte(`"n":1`), []byte(`"n":7`), 1))); err == nil {
+		t.Errorf("verify changed segment should return an error")
+	}
+	if err := VerifySegment(bytes.NewReader(data[:bytes.LastIndex(data, segmentFooter)])); err == nil {
+		t.Errorf("verify truncated segment should return an error")
+	}
+}
+
+func TestSegmentMarkerReaders(t *testing.T) {
+	header := []byte(`{"segment":"header","time":"2019-07-10T05:35:54Z","host":"shire","pid":1234}` + "\n")
+	footer := []byte(`{"segment":"footer","time":"2019-07-10T05:35:58Z","entries":0,"checksum":"crc32c:00000000"}` + "\n")
+	if !IsSegmentMarker(header) || !IsSegmentMarker(footer) || IsSegmentMarker([]byte(`{"time":"2019-07-10T05:35:54Z","segment":"header"}`)) {
+		t.Errorf("IsSegmentMarker not correct")
+	}
+
+	var buf bytes.Buffer
+	w := &ConsoleWriter{Writer: &buf}
+	for _, line := range [][]byte{header, []byte(`{"time":"2019-07-10T05:35:55Z","level":"info","message":"hello"}` + "\n"), footer} {
+		if n, err := w.Write(line); IsSegmentMarker(line) && n != len(line) || err != nil {
+			t.Errorf("console writer write %d, %+v", n, err)
+		}
+	}
+	if buf.String() != "2019-07-10T05:35:55Z INF > hello\n" {
+		t.Errorf("console writer output not correct: %q", buf.String())
+	}
+}
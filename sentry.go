@@ -0,0 +1,408 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SentryWriter is an Writer that sends error entries to Sentry, or a compatible service,
+// as envelope events.
+//
+// The entries are posted synchronously within Timeout, wrap it, or a MultiLevelWriter holding it,
+// with AsyncWriter to keep the logging path fast.
+type SentryWriter struct {
+	// DSN specifies the Sentry DSN, e.g. "https://public@o1.ingest.sentry.io/42".
+	DSN string
+
+	// Level specifies the minimum level of the entries to send, ErrorLevel if zero.
+	Level Level
+
+	// Tags specifies the fields sent as the event tags, other fields are sent as extra data.
+	Tags []string
+
+	// Environment specifies the optional environment of the events, e.g. "production".
+	Environment string
+
+	// Release specifies the optional release of the events, e.g. "myapp@1.2.3".
+	Release string
+
+	// ServerName specifies the server name of the events, the hostname if empty.
+	ServerName string
+
+	// RateLimit specifies the maximum events per second with bursts of the same size,
+	// zero means no limit. Events over the limit are dropped.
+	RateLimit float64
+
+	// Client specifies the http client, http.DefaultClient if nil.
+	Client *http.Client
+
+	// Timeout specifies the timeout of posting an event, 5 seconds if zero.
+	Timeout time.Duration
+
+	once     sync.Once
+	err      error
+	endpoint string
+	auth     string
+
+	mu         sync.Mutex
+	tokens     float64
+	last       time.Time
+	retryAfter time.Time
+}
+
+// WriteEntry implements Writer.
+func (w *SentryWriter) WriteEntry(e *Entry) (n int, err error) {
+	w.once.Do(w.init)
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	level := w.Level
+	if level == 0 {
+		level = ErrorLevel
+	}
+	if e.Level < level || e.Level == noLevel {
+		return 0, nil
+	}
+
+	if !w.allow() {
+		return 0, nil
+	}
+
+	b := bbpool.Get().(*bb)
+	b.B = append(b.B[:0], e.buf...)
+	defer bbpool.Put(b)
+
+	var args FormatterArgs
+	parseFormatterArgs(b.B, &args)
+
+	body, err := w.envelope(e.Level, &args)
+	if err != nil {
+		return 0, err
+	}
+
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", w.auth)
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		w.backoff(resp.Header.Get("Retry-After"))
+		return 0, fmt.Errorf("sentry: rate limited: %s", resp.Status)
+	case resp.StatusCode/100 != 2:
+		return 0, fmt.Errorf("sentry: unexpected status: %s", resp.Status)
+	}
+
+	return len(e.buf), nil
+}
+
+func (w *SentryWriter) init() {
+	u, err := url.Parse(w.DSN)
+	if err != nil {
+		w.err = fmt.Errorf("sentry: invalid dsn: %w", err)
+		return
+	}
+	if u.User == nil || u.User.Username() == "" {
+		w.err = errors.New("sentry: invalid dsn: missing public key")
+		return
+	}
+	dir, project := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if project == "" {
+		w.err = errors.New("sentry: invalid dsn: missing project id")
+		return
+	}
+
+	w.endpoint = u.Scheme + "://" + u.Host + dir + "api/" + project + "/envelope/"
+	w.auth = "Sentry sentry_version=7, sentry_client=logstack/1.0, sentry_key=" + u.User.Username()
+	if secret, ok := u.User.Password(); ok {
+		w.auth += ", sentry_secret=" + secret
+	}
+	w.tokens = w.RateLimit
+	w.last = timeNow()
+}
+
+// allow reports whether an event can be sent by the rate limit of the writer and the server.
+func (w *SentryWriter) allow() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := timeNow()
+	if now.Before(w.retryAfter) {
+		return false
+	}
+	if w.RateLimit <= 0 {
+		return true
+	}
+
+	w.tokens += now.Sub(w.last).Seconds() * w.RateLimit
+	if w.tokens > w.RateLimit {
+		w.tokens = w.RateLimit
+	}
+	w.last = now
+	if w.tokens < 1 {
+		return false
+	}
+	w.tokens--
+	return true
+}
+
+// backoff stops sending events for the seconds of the Retry-After header, 60 seconds by default.
+func (w *SentryWriter) backoff(retryAfter string) {
+	seconds, err := strconv.Atoi(retryAfter)
+	if err != nil || seconds <= 0 {
+		seconds = 60
+	}
+	w.mu.Lock()
+	w.retryAfter = timeNow().Add(time.Duration(seconds) * time.Second)
+	w.mu.Unlock()
+}
+
+type sentryEvent struct {
+	EventID     string                     `json:"event_id"`
+	Timestamp   string                     `json:"timestamp,omitempty"`
+	Platform    string                     `json:"platform"`
+	Level       string                     `json:"level"`
+	Logger      string                     `json:"logger"`
+	ServerName  string                     `json:"server_name,omitempty"`
+	Environment string                     `json:"environment,omitempty"`
+	Release     string                     `json:"release,omitempty"`
+	Message     *sentryMessage             `json:"message,omitempty"`
+	Tags        map[string]string          `json:"tags,omitempty"`
+	Extra       map[string]json.RawMessage `json:"extra,omitempty"`
+	Exception   *sentryException           `json:"exception,omitempty"`
+	Fingerprint []string                   `json:"fingerprint,omitempty"`
+}
+
+type sentryMessage struct {
+	Formatted string `json:"formatted"`
+}
+
+type sentryException struct {
+	Values []sentryExceptionValue `json:"values"`
+}
+
+type sentryExceptionValue struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+// envelope builds a Sentry envelope with a single event of the formatter args.
+func (w *SentryWriter) envelope(level Level, args *FormatterArgs) ([]byte, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+
+	event := sentryEvent{
+		EventID:     fmt.Sprintf("%x", id),
+		Timestamp:   args.Time,
+		Platform:    "go",
+		Level:       sentryLevel(level),
+		Logger:      "logstack",
+		ServerName:  w.ServerName,
+		Environment: w.Environment,
+		Release:     w.Release,
+	}
+	if event.ServerName == "" {
+		event.ServerName = hostname
+	}
+	if args.Message != "" {
+		event.Message = &sentryMessage{Formatted: args.Message}
+	}
+	if args.Caller != "" {
+		event.Tags = map[string]string{"caller": args.Caller}
+		event.Fingerprint = append(event.Fingerprint, args.Caller)
+	}
+	if args.Message != "" {
+		event.Fingerprint = append(event.Fingerprint, args.Message)
+	}
+
+	var exception *sentryExceptionValue
+	for _, kv := range args.KeyValues {
+		if kv.Key == "error" && kv.ValueType == 's' {
+			exception = &sentryExceptionValue{Type: "error", Value: kv.Value}
+			continue
+		}
+		if w.isTag(kv.Key) {
+			if event.Tags == nil {
+				event.Tags = make(map[string]string)
+			}
+			event.Tags[kv.Key] = kv.Value
+			continue
+		}
+		if event.Extra == nil {
+			event.Extra = make(map[string]json.RawMessage)
+		}
+		if kv.ValueType == 's' {
+			event.Extra[kv.Key], _ = json.Marshal(kv.Value)
+		} else {
+			event.Extra[kv.Key] = json.RawMessage(kv.Value)
+		}
+	}
+	if args.Goid != "" {
+		if event.Extra == nil {
+			event.Extra = make(map[string]json.RawMessage)
+		}
+		event.Extra["goid"] = json.RawMessage(args.Goid)
+	}
+
+	if args.Stack != "" {
+		if exception == nil {
+			exception = &sentryExceptionValue{Type: "error", Value: args.Message}
+		}
+		if frames := parseSentryFrames(args.Stack); len(frames) != 0 {
+			exception.Stacktrace = &sentryStacktrace{Frames: frames}
+		}
+	}
+	if exception != nil {
+		event.Exception = &sentryException{Values: []sentryExceptionValue{*exception}}
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	header, _ := json.Marshal(map[string]string{
+		"event_id": event.EventID,
+		"sent_at":  timeNow().UTC().Format(time.RFC3339Nano),
+		"dsn":      w.DSN,
+	})
+	b.Write(header)
+	fmt.Fprintf(&b, "\n{\"type\":\"event\",\"length\":%d}\n", len(payload))
+	b.Write(payload)
+	b.WriteByte('\n')
+
+	return b.Bytes(), nil
+}
+
+func (w *SentryWriter) isTag(key string) bool {
+	for _, tag := range w.Tags {
+		if tag == key {
+			return true
+		}
+	}
+	return false
+}
+
+func sentryLevel(level Level) string {
+	switch level {
+	case TraceLevel, DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warning"
+	case ErrorLevel:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
+
+// parseSentryFrames parses the stack trace of Entry.Stack to Sentry frames, the outermost call first.
+func parseSentryFrames(stack string) (frames []sentryFrame) {
+	pkg := reflect.TypeOf(Entry{}).PkgPath() + "."
+
+	lines := strings.Split(stack, "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		fn, loc := lines[i], lines[i+1]
+		if fn == "" || fn[0] == '\t' || !strings.HasPrefix(loc, "\t") || strings.HasPrefix(fn, "goroutine ") {
+			continue
+		}
+		i++
+
+		// function
+		fn = strings.TrimPrefix(fn, "created by ")
+		if j := strings.Index(fn, " in goroutine "); j >= 0 {
+			fn = fn[:j]
+		}
+		if strings.HasSuffix(fn, ")") {
+			if j := strings.LastIndexByte(fn, '('); j > 0 {
+				fn = fn[:j]
+			}
+		}
+		if fn == pkg+"stacks" || fn == pkg+"(*Entry).Stack" {
+			continue
+		}
+
+		// location
+		loc = strings.TrimPrefix(loc, "\t")
+		if j := strings.LastIndex(loc, " +0x"); j >= 0 {
+			loc = loc[:j]
+		}
+		frame := sentryFrame{AbsPath: loc}
+		if j := strings.LastIndexByte(loc, ':'); j >= 0 {
+			frame.AbsPath = loc[:j]
+			frame.Lineno, _ = strconv.Atoi(loc[j+1:])
+		}
+		frame.Filename = path.Base(frame.AbsPath)
+
+		// module
+		frame.Module, frame.Function = "", fn
+		slash := strings.LastIndexByte(fn, '/')
+		if j := strings.IndexByte(fn[slash+1:], '.'); j >= 0 {
+			frame.Module, frame.Function = fn[:slash+1+j], fn[slash+1+j+1:]
+		}
+		frame.InApp = frame.Module == "main" || strings.Contains(strings.SplitN(frame.Module, "/", 2)[0], ".")
+
+		frames = append(frames, frame)
+	}
+
+	// sentry expects the outermost call first
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return
+}
+
+var _ Writer = (*SentryWriter)(nil)
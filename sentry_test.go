@@ -0,0 +1,247 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type sentryServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	paths    []string
+	auths    []string
+	headers  []map[string]interface{}
+	events   []map[string]interface{}
+	requests int
+}
+
+func newSentryServer(t *testing.T) *sentryServer {
+	s := &sentryServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests++
+		s.paths = append(s.paths, req.URL.Path)
+		s.auths = append(s.auths, req.Header.Get("X-Sentry-Auth"))
+
+		br := bufio.NewReader(req.Body)
+		var header, item, event map[string]interface{}
+		for _, v := range []*map[string]interface{}{&header, &item, &event} {
+			line, _ := br.ReadBytes('\n')
+			if err := json.Unmarshal(line, v); err != nil {
+				t.Errorf("sentry envelope line %q error: %+v", line, err)
+			}
+		}
+		if item["type"] != "event" {
+			t.Errorf("sentry envelope item type: %v", item["type"])
+		}
+		s.headers = append(s.headers, header)
+		s.events = append(s.events, event)
+
+		if s.status == http.StatusTooManyRequests {
+			rw.Header().Set("Retry-After", "30")
+		}
+		rw.WriteHeader(s.status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *sentryServer) dsn() string {
+	return strings.Replace(s.URL, "://", "://public:secret@", 1) + "/42"
+}
+
+func TestSentryWriter(t *testing.T) {
+	server := newSentryServer(t)
+
+	logger := Logger{
+		Level:  InfoLevel,
+		Caller: 1,
+		Writer: &SentryWriter{
+			DSN:         server.dsn(),
+			Tags:        []string{"user"},
+			Environment: "test",
+			Release:     "app@1.0.0",
+		},
+	}
+
+	logger.Info().Str("user", "alice").Msg("not sent")
+	logger.Error().Err(errors.New("connection refused")).Str("user", "alice").Int("attempt", 3).Stack().Msg("dial failed")
+
+	if server.requests != 1 {
+		t.Fatalf("sentry writer should send only error entries, requests=%d", server.requests)
+	}
+	if got := server.paths[0]; got != "/api/42/envelope/" {
+		t.Errorf("sentry writer endpoint: %s", got)
+	}
+	if got := server.auths[0]; !strings.Contains(got, "sentry_key=public") || !strings.Contains(got, "sentry_secret=secret") {
+		t.Errorf("sentry writer auth header: %s", got)
+	}
+
+	event := server.events[0]
+	if event["event_id"] != server.headers[0]["event_id"] || len(event["event_id"].(string)) != 32 {
+		t.Errorf("sentry writer event id: %v", event["event_id"])
+	}
+	for key, want := range map[string]interface{}{
+		"level":       "error",
+		"environment": "test",
+		"release":     "app@1.0.0",
+		"platform":    "go",
+	} {
+		if event[key] != want {
+			t.Errorf("sentry writer event %s want=%v got=%v", key, want, event[key])
+		}
+	}
+	if msg := event["message"].(map[string]interface{}); msg["formatted"] != "dial failed" {
+		t.Errorf("sentry writer message: %v", msg)
+	}
+	tags := event["tags"].(map[string]interface{})
+	if tags["user"] != "alice" || !strings.HasPrefix(tags["caller"].(string), "sentry_test.go:") {
+		t.Errorf("sentry writer tags: %v", tags)
+	}
+	if extra := event["extra"].(map[string]interface{}); extra["attempt"] != float64(3) {
+		t.Errorf("sentry writer extra: %v", extra)
+	}
+	if fp := event["fingerprint"].([]interface{}); len(fp) != 2 || fp[0] != tags["caller"] || fp[1] != "dial failed" {
+		t.Errorf("sentry writer fingerprint: %v", fp)
+	}
+
+	values := event["exception"].(map[string]interface{})["values"].([]interface{})
+	exception := values[0].(map[string]interface{})
+	if exception["value"] != "connection refused" {
+		t.Errorf("sentry writer exception: %v", exception)
+	}
+	frames := exception["stacktrace"].(map[string]interface{})["frames"].([]interface{})
+	last := frames[len(frames)-1].(map[string]interface{})
+	if last["function"] != "TestSentryWriter" || last["filename"] != "sentry_test.go" || last["in_app"] != true {
+		t.Errorf("sentry writer innermost frame: %v", last)
+	}
+}
+
+func TestSentryWriterFrames(t *testing.T) {
+	stack := `goroutine 7 [running]:
+github.com/fabricatorsltd/logstack.stacks(0x0)
+	/src/logstack/logger.go:2119 +0x85
+github.com/fabricatorsltd/logstack.(*Entry).Stack(0xc000130000)
+	/src/logstack/logger.go:1553 +0xa5
+main.(*server).handle(0xc0000a2000, {0x6b4f20, 0xc0000b6000})
+	/src/app/server.go:42 +0x1d2
+net/http.HandlerFunc.ServeHTTP(...)
+	/usr/local/go/src/net/http/server.go:2166
+created by net/http.(*Server).Serve in goroutine 1
+	/usr/local/go/src/net/http/server.go:3285 +0x4b4
+`
+	frames := parseSentryFrames(stack)
+	want := []sentryFrame{
+		{Function: "(*Server).Serve", Module: "net/http", Filename: "server.go", AbsPath: "/usr/local/go/src/net/http/server.go", Lineno: 3285},
+		{Function: "HandlerFunc.ServeHTTP", Module: "net/http", Filename: "server.go", AbsPath: "/usr/local/go/src/net/http/server.go", Lineno: 2166},
+		{Function: "(*server).handle", Module: "main", Filename: "server.go", AbsPath: "/src/app/server.go", Lineno: 42, InApp: true},
+	}
+	if len(frames) != len(want) {
+		t.Fatalf("sentry frames want=%+v got=%+v", want, frames)
+	}
+	for i := range want {
+		if frames[i] != want[i] {
+			t.Errorf("sentry frame %d want=%+v got=%+v", i, want[i], frames[i])
+		}
+	}
+}
+
+func TestSentryWriterRateLimit(t *testing.T) {
+	server := newSentryServer(t)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	w := &SentryWriter{DSN: server.dsn(), RateLimit: 2}
+	for i := 0; i < 5; i++ {
+		_, _ = wlprintf(w, ErrorLevel, `{"level":"error","message":"burst %d"}`, i)
+	}
+	if server.requests != 2 {
+		t.Errorf("sentry writer should allow a burst of 2, requests=%d", server.requests)
+	}
+
+	now = now.Add(time.Second)
+	_, _ = wlprintf(w, ErrorLevel, `{"level":"error","message":"refilled"}`)
+	if server.requests != 3 {
+		t.Errorf("sentry writer should refill tokens, requests=%d", server.requests)
+	}
+
+	// server side rate limit
+	server.status = http.StatusTooManyRequests
+	now = now.Add(time.Second)
+	if _, err := wlprintf(w, ErrorLevel, `{"level":"error","message":"limited"}`); err == nil {
+		t.Errorf("sentry writer should return 429 error")
+	}
+	server.status = http.StatusOK
+	now = now.Add(10 * time.Second)
+	_, _ = wlprintf(w, ErrorLevel, `{"level":"error","message":"backoff"}`)
+	if server.requests != 4 {
+		t.Errorf("sentry writer should back off after 429, requests=%d", server.requests)
+	}
+	now = now.Add(30 * time.Second)
+	_, _ = wlprintf(w, ErrorLevel, `{"level":"error","message":"resumed"}`)
+	if server.requests != 5 {
+		t.Errorf("sentry writer should resume after Retry-After, requests=%d", server.requests)
+	}
+}
+
+func TestSentryWriterTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	w := &SentryWriter{
+		DSN:     strings.Replace(server.URL, "://", "://public@", 1) + "/42",
+		Timeout: 50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	if _, err := wlprintf(w, ErrorLevel, `{"level":"error","message":"slow"}`); err == nil {
+		t.Errorf("sentry writer should return an error on timeout")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("sentry writer timeout not honoured, took %s", d)
+	}
+}
+
+func TestSentryWriterInvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"", "https://sentry.io/42", "https://public@sentry.io/", "%zz"} {
+		w := &SentryWriter{DSN: dsn}
+		if _, err := wlprintf(w, ErrorLevel, `{"level":"error"}`); err == nil {
+			t.Errorf("sentry writer dsn %q should error", dsn)
+		}
+	}
+}
+
+func BenchmarkSentryWriter(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+	}))
+	defer server.Close()
+
+	logger := Logger{
+		Writer: &SentryWriter{DSN: strings.Replace(server.URL, "://", "://public@", 1) + "/42"},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Error().Str("foo", "bar").Msg("hello sentry")
+	}
+}
@@ -0,0 +1,313 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"debug/elf"
+	"debug/gosym"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StackPCs adds the "stack_pcs" key with the raw program counters of the current goroutine, a
+// compact alternative to Stack symbolised later from the binary by Symbolizer or `logstack symbolize`.
+//
+// The value is the build ID of the binary followed by the hex offsets of the program counters
+// from a function of the package, e.g. "Wd7Ds1L5Y2CLv8xCd4mX:-3c2a1,1f20,2b8e0", so that it
+// does not depend on the load address of the binary.
+func (e *Entry) StackPCs() *Entry {
+	if e == nil {
+		return nil
+	}
+	checkEntry(e)
+
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+
+	e.buf = append(e.buf, ",\"stack_pcs\":\""...)
+	e.buf = append(e.buf, executableBuildID()...)
+	e.buf = append(e.buf, ':')
+	base := stackPCBase()
+	for i, pc := range pcs[:n] {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		if pc < base {
+			e.buf = append(e.buf, '-')
+			e.buf = strconv.AppendUint(e.buf, uint64(base-pc), 16)
+		} else {
+			e.buf = strconv.AppendUint(e.buf, uint64(pc-base), 16)
+		}
+	}
+	e.buf = append(e.buf, '"')
+	return e
+}
+
+// stackPCAnchor is the function which the program counters of StackPCs are relative to.
+func stackPCAnchor() {}
+
+const stackPCAnchorName = "github.com/fabricatorsltd/logstack.stackPCAnchor"
+
+var stackPCBase = func() func() uintptr {
+	var once sync.Once
+	var base uintptr
+	return func() uintptr {
+		once.Do(func() {
+			base = runtime.FuncForPC(reflect.ValueOf(stackPCAnchor).Pointer()).Entry()
+		})
+		return base
+	}
+}()
+
+var executableBuildID = func() func() string {
+	var once sync.Once
+	var id string
+	return func() string {
+		once.Do(func() {
+			if name, err := os.Executable(); err == nil {
+				id, _ = ReadBuildID(name)
+			}
+		})
+		return id
+	}
+}()
+
+// ReadBuildID returns the content ID of a Go binary, the last component of its Go build ID.
+func ReadBuildID(name string) (string, error) {
+	id, err := readGoBuildID(name)
+	if err != nil {
+		return "", err
+	}
+	return id[strings.LastIndexByte(id, '/')+1:], nil
+}
+
+func readGoBuildID(name string) (string, error) {
+	if f, err := elf.Open(name); err == nil {
+		defer f.Close()
+		// the note of namesz, descsz, type, "Go\x00\x00" and the build ID
+		if sect := f.Section(".note.go.buildid"); sect != nil {
+			data, err := sect.Data()
+			if err != nil || len(data) < 16 {
+				return "", errors.New("log: invalid go build id in " + name)
+			}
+			size := int(f.ByteOrder.Uint32(data[4:]))
+			if size > len(data)-16 {
+				return "", errors.New("log: invalid go build id in " + name)
+			}
+			return string(data[16 : 16+size]), nil
+		}
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// the linker puts the build ID at the start of the text segment of the other binary formats
+	marker := []byte("\xff Go build ID: \"")
+	br := bufio.NewReaderSize(file, 64*1024)
+	var window []byte
+	for {
+		b, err := br.Peek(64 * 1024)
+		if i := bytes.Index(b, marker); i >= 0 {
+			window = b[i+len(marker):]
+			break
+		}
+		if err != nil {
+			return "", errors.New("log: go build id not found in " + name)
+		}
+		_, _ = br.Discard(len(b) - len(marker))
+	}
+	end := bytes.IndexByte(window, '"')
+	if end < 0 {
+		return "", errors.New("log: invalid go build id in " + name)
+	}
+	return string(window[:end]), nil
+}
+
+// Symbolizer symbolises the program counters of StackPCs with the symbol tables of the binary
+// which logged them. Inlined calls are attributed to the functions they are inlined into.
+type Symbolizer struct {
+	buildID string
+	base    uint64
+	table   *gosym.Table
+}
+
+// NewSymbolizer reads the symbol tables of a Go binary in the ELF, Mach-O or PE format.
+func NewSymbolizer(binary string) (*Symbolizer, error) {
+	buildID, err := ReadBuildID(binary)
+	if err != nil {
+		return nil, err
+	}
+
+	pclntab, text, err := readPCLineTable(binary)
+	if err != nil {
+		return nil, fmt.Errorf("log: read symbol tables of %s: %w", binary, err)
+	}
+	table, err := gosym.NewTable(nil, gosym.NewLineTable(pclntab, text))
+	if err != nil {
+		return nil, fmt.Errorf("log: read symbol tables of %s: %w", binary, err)
+	}
+	fn := table.LookupFunc(stackPCAnchorName)
+	if fn == nil {
+		return nil, fmt.Errorf("log: %s is not linked with %s", binary, stackPCAnchorName[:strings.LastIndexByte(stackPCAnchorName, '.')])
+	}
+
+	return &Symbolizer{buildID: buildID, base: fn.Entry, table: table}, nil
+}
+
+func readPCLineTable(binary string) (pclntab []byte, text uint64, err error) {
+	if f, err := elf.Open(binary); err == nil {
+		defer f.Close()
+		sect, textSect := f.Section(".gopclntab"), f.Section(".text")
+		if sect == nil || textSect == nil {
+			return nil, 0, errors.New("missing .gopclntab section")
+		}
+		pclntab, err = sect.Data()
+		return pclntab, textSect.Addr, err
+	}
+
+	if f, err := macho.Open(binary); err == nil {
+		defer f.Close()
+		sect, textSect := f.Section("__gopclntab"), f.Section("__text")
+		if sect == nil || textSect == nil {
+			return nil, 0, errors.New("missing __gopclntab section")
+		}
+		pclntab, err = sect.Data()
+		return pclntab, textSect.Addr, err
+	}
+
+	f, err := pe.Open(binary)
+	if err != nil {
+		return nil, 0, errors.New("unknown binary format")
+	}
+	defer f.Close()
+	var imageBase uint64
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		imageBase = uint64(oh.ImageBase)
+	case *pe.OptionalHeader64:
+		imageBase = oh.ImageBase
+	}
+	var start, end *pe.Symbol
+	for _, s := range f.Symbols {
+		switch s.Name {
+		case "runtime.pclntab":
+			start = s
+		case "runtime.epclntab":
+			end = s
+		}
+	}
+	textSect := f.Section(".text")
+	if start == nil || end == nil || textSect == nil || start.SectionNumber <= 0 || int(start.SectionNumber) > len(f.Sections) {
+		return nil, 0, errors.New("missing runtime.pclntab symbol")
+	}
+	data, err := f.Sections[start.SectionNumber-1].Data()
+	if err != nil {
+		return nil, 0, err
+	}
+	if start.Value > end.Value || int(end.Value) > len(data) {
+		return nil, 0, errors.New("invalid runtime.pclntab symbol")
+	}
+	return data[start.Value:end.Value], imageBase + uint64(textSect.VirtualAddress), nil
+}
+
+// BuildID returns the content ID of the binary, see ReadBuildID.
+func (s *Symbolizer) BuildID() string {
+	return s.buildID
+}
+
+// Symbolize returns the readable stack trace of a "stack_pcs" value, in the format of runtime.Stack without the goroutine header.
+// It returns an error if the value was logged by another binary.
+func (s *Symbolizer) Symbolize(pcs string) (string, error) {
+	i := strings.LastIndexByte(pcs, ':')
+	if i < 0 {
+		return "", errors.New("log: invalid stack_pcs value")
+	}
+	if pcs[:i] != s.buildID {
+		return "", fmt.Errorf("log: stack_pcs of build id %q, the binary is %q", pcs[:i], s.buildID)
+	}
+
+	var b strings.Builder
+	for _, off := range strings.Split(pcs[i+1:], ",") {
+		neg := strings.HasPrefix(off, "-")
+		n, err := strconv.ParseUint(strings.TrimPrefix(off, "-"), 16, 64)
+		if err != nil {
+			return "", errors.New("log: invalid stack_pcs value")
+		}
+		pc := s.base + n
+		if neg {
+			pc = s.base - n
+		}
+
+		// the program counters are return addresses, look up the call instructions
+		file, line, fn := s.table.PCToLine(pc - 1)
+		if fn == nil {
+			fmt.Fprintf(&b, "?()\n\t?:0 pc=%#x\n", pc)
+			continue
+		}
+		if fn.Name == "runtime.goexit" {
+			break
+		}
+		fmt.Fprintf(&b, "%s(...)\n\t%s:%d +%#x\n", fn.Name, file, line, pc-fn.Entry)
+	}
+	return b.String(), nil
+}
+
+// SymbolizeEntries copies the JSON log lines of src to dst, replacing the "stack_pcs" fields
+// logged by the binary with the "stack" fields of readable stack traces. It returns the number of replaced fields.
+func (s *Symbolizer) SymbolizeEntries(dst io.Writer, src io.Reader) (n int, err error) {
+	br := bufio.NewReaderSize(src, 64*1024)
+	bw := bufio.NewWriterSize(dst, 64*1024)
+	var out []byte
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			members, _ := jsonMembers(line)
+			for _, m := range members {
+				if m.key != "stack_pcs" || line[m.start] != '"' {
+					continue
+				}
+				stack, err := s.Symbolize(m.value(line))
+				if err != nil {
+					break
+				}
+				keyStart := bytes.LastIndex(line[:m.start], []byte(`"stack_pcs"`))
+				out = append(out[:0], line[:keyStart]...)
+				out = append(out, `"stack":"`...)
+				out = appendEscaped(out, stack)
+				out = append(out, '"')
+				line = append(out, line[m.end:]...)
+				n++
+				break
+			}
+			if _, err := bw.Write(line); err != nil {
+				return n, err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
+
+// appendEscaped appends a string escaped as the content of a JSON string.
+func appendEscaped(dst []byte, s string) []byte {
+	e := Entry{buf: dst}
+	e.string(s)
+	return e.buf
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestStackPCs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Logger{Writer: IOWriter{&buf}}
+	logger.Error().StackPCs().Msg("compact stack")
+
+	matches := regexp.MustCompile(`"stack_pcs":"([^"]+)"`).FindStringSubmatch(buf.String())
+	if matches == nil {
+		t.Fatalf("stack_pcs not found: %s", buf.String())
+	}
+	if len(matches[1]) > 400 {
+		t.Errorf("stack_pcs is not compact: %d bytes", len(matches[1]))
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		t.Skipf("os.Executable error: %+v", err)
+	}
+	s, err := NewSymbolizer(binary)
+	if err != nil {
+		t.Fatalf("NewSymbolizer error: %+v", err)
+	}
+	if !strings.HasPrefix(matches[1], s.BuildID()+":") || s.BuildID() == "" {
+		t.Errorf("stack_pcs build id not correct: %s, want %s", matches[1], s.BuildID())
+	}
+
+	stack, err := s.Symbolize(matches[1])
+	if err != nil {
+		t.Fatalf("Symbolize error: %+v", err)
+	}
+	lines := strings.Split(stack, "\n")
+	if !strings.HasPrefix(lines[0], "github.com/fabricatorsltd/logstack.TestStackPCs(") || !strings.Contains(lines[1], "stackpc_test.go:14 +0x") {
+		t.Errorf("symbolized stack not correct:\n%s", stack)
+	}
+	if !strings.Contains(stack, "testing.tRunner(") {
+		t.Errorf("symbolized stack should contain the callers:\n%s", stack)
+	}
+
+	if _, err = s.Symbolize("other:1f,2a"); err == nil {
+		t.Errorf("Symbolize of another build id should return an error")
+	}
+
+	var out bytes.Buffer
+	input := buf.String() + `{"level":"error","stack_pcs":"other:1f","message":"other binary"}` + "\n"
+	n, err := s.SymbolizeEntries(&out, strings.NewReader(input))
+	if err != nil || n != 1 {
+		t.Fatalf("SymbolizeEntries not correct: %d %+v", n, err)
+	}
+	got := strings.Split(out.String(), "\n")
+	if !strings.Contains(got[0], `"stack":"github.com/fabricatorsltd/logstack.TestStackPCs(...)\n\t`) || strings.Contains(got[0], "stack_pcs") {
+		t.Errorf("SymbolizeEntries output not correct: %s", got[0])
+	}
+	if got[1]+"\n" != input[len(buf.String()):] {
+		t.Errorf("SymbolizeEntries should keep the entries of other binaries: %s", got[1])
+	}
+}
+
+func BenchmarkStackPCs(b *testing.B) {
+	logger := Logger{Writer: IOWriter{&bytes.Buffer{}}}
+	for i := 0; i < b.N; i++ {
+		logger.Error().StackPCs().Msg("hello world")
+	}
+}
@@ -1,7 +1,11 @@
 package log
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"net"
+	"os"
 	"strconv"
 	"sync"
 	"time"
@@ -9,7 +13,8 @@ import (
 
 // SyslogWriter is an Writer that writes logs to a syslog server..
 type SyslogWriter struct {
-	// Network specifies network of the syslog server
+	// Network specifies network of the syslog server, using tcp if empty and TLS is enabled.
+	// If both Network and Address are empty, the local syslog socket is discovered.
 	Network string
 
 	// Address specifies address of the syslog server
@@ -27,11 +32,34 @@ type SyslogWriter struct {
 	// Dial specifies the dial function for creating TCP/TLS connections.
 	Dial func(network, addr string) (net.Conn, error)
 
+	// TLSConfig specifies the TLS configuration of the connection, e.g. ServerName. TLS is enabled
+	// if it is set or if CertFile or CAFile is set, the ServerName defaults to the host of Address.
+	TLSConfig *tls.Config
+
+	// CertFile and KeyFile specify the PEM files of the client certificate for mutual TLS.
+	CertFile string
+	KeyFile  string
+
+	// CAFile specifies the PEM file of the CA certificates verifying the server, using the system roots if empty.
+	CAFile string
+
+	// ReloadInterval specifies the interval of checking CertFile, KeyFile and CAFile for changes,
+	// using 1 minute if zero. The connection is reestablished with the reloaded files.
+	ReloadInterval time.Duration
+
 	mu    sync.Mutex
 	conn  net.Conn
 	local bool
+
+	tlsCert    *tls.Certificate
+	tlsRoots   *x509.CertPool
+	tlsMtimes  [3]int64
+	tlsChecked time.Time
 }
 
+// syslogLocalAddresses is the local syslog sockets tried in turn by SyslogWriter, as log/syslog does.
+var syslogLocalAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
 // Close closes a connection to the syslog server.
 func (w *SyslogWriter) Close() (err error) {
 	w.mu.Lock()
@@ -57,13 +85,33 @@ func (w *SyslogWriter) connect() (err error) {
 		dial = net.Dial
 	}
 
-	w.conn, err = dial(w.Network, w.Address)
-	if err != nil {
-		return
+	if w.Network == "" && w.Address == "" {
+		for _, network := range []string{"unixgram", "unix"} {
+			for _, address := range syslogLocalAddresses {
+				if w.conn, err = dial(network, address); err == nil {
+					break
+				}
+			}
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return errors.New("log: local syslog socket not found")
+		}
+		w.local = true
+	} else if w.tlsEnabled() {
+		if w.conn, err = w.dialTLS(dial); err != nil {
+			return
+		}
+		w.local = false
+	} else {
+		if w.conn, err = dial(w.Network, w.Address); err != nil {
+			return
+		}
+		w.local = w.Address != "" && w.Address[0] == '/'
 	}
 
-	w.local = w.Address != "" && w.Address[0] == '/'
-
 	if w.Hostname == "" {
 		if w.local {
 			w.Hostname = hostname
@@ -75,6 +123,94 @@ func (w *SyslogWriter) connect() (err error) {
 	return
 }
 
+func (w *SyslogWriter) tlsEnabled() bool {
+	return w.TLSConfig != nil || w.CertFile != "" || w.CAFile != ""
+}
+
+// dialTLS makes a TLS connection to the syslog server with the loaded CertFile, KeyFile and CAFile.
+func (w *SyslogWriter) dialTLS(dial func(network, addr string) (net.Conn, error)) (net.Conn, error) {
+	if (w.CertFile != "" && w.tlsCert == nil) || (w.CAFile != "" && w.tlsRoots == nil) {
+		if _, err := w.loadTLSFiles(timeNow()); err != nil {
+			return nil, err
+		}
+	}
+
+	config := &tls.Config{}
+	if w.TLSConfig != nil {
+		config = w.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		if host, _, err := net.SplitHostPort(w.Address); err == nil {
+			config.ServerName = host
+		}
+	}
+	if w.tlsRoots != nil {
+		config.RootCAs = w.tlsRoots
+	}
+	if w.tlsCert != nil {
+		config.Certificates = []tls.Certificate{*w.tlsCert}
+	}
+
+	network := w.Network
+	if network == "" {
+		network = "tcp"
+	}
+	conn, err := dial(network, w.Address)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// loadTLSFiles loads CertFile, KeyFile and CAFile if they changed since the last load, it reports whether they are reloaded.
+// The loaded files are kept if the changed ones are invalid.
+func (w *SyslogWriter) loadTLSFiles(now time.Time) (reloaded bool, err error) {
+	w.tlsChecked = now
+
+	var mtimes [3]int64
+	for i, name := range []string{w.CertFile, w.KeyFile, w.CAFile} {
+		if name == "" {
+			continue
+		}
+		st, err := os.Stat(name)
+		if err != nil {
+			return false, err
+		}
+		mtimes[i] = st.ModTime().UnixNano()
+	}
+	if mtimes == w.tlsMtimes {
+		return false, nil
+	}
+
+	var cert *tls.Certificate
+	if w.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(w.CertFile, w.KeyFile)
+		if err != nil {
+			return false, err
+		}
+		cert = &c
+	}
+	var roots *x509.CertPool
+	if w.CAFile != "" {
+		data, err := os.ReadFile(w.CAFile)
+		if err != nil {
+			return false, err
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			return false, errors.New("log: no certificates found in " + w.CAFile)
+		}
+	}
+
+	w.tlsCert, w.tlsRoots, w.tlsMtimes = cert, roots, mtimes
+	return true, nil
+}
+
 // WriteEntry implements Writer, sends logs with priority to the syslog server.
 func (w *SyslogWriter) WriteEntry(e *Entry) (n int, err error) {
 	if w.conn == nil {
@@ -140,6 +276,19 @@ func (w *SyslogWriter) WriteEntry(e *Entry) (n int, err error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	if w.CertFile != "" || w.CAFile != "" {
+		interval := w.ReloadInterval
+		if interval == 0 {
+			interval = time.Minute
+		}
+		if now := timeNow(); now.Sub(w.tlsChecked) >= interval {
+			if reloaded, _ := w.loadTLSFiles(now); reloaded && w.conn != nil {
+				w.conn.Close()
+				w.conn = nil
+			}
+		}
+	}
+
 	if w.conn != nil {
 		if n, err := w.conn.Write(e1.buf); err == nil {
 			return n, err
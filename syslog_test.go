@@ -1,8 +1,20 @@
 package log
 
 import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
 	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -111,3 +123,197 @@ func TestSyslogWriterUnix(t *testing.T) {
 	_, err = wlprintf(w, InfoLevel, "a long long long long message again.\n")
 	t.Logf("write syslog writer error: %+v", err)
 }
+
+// writeTestCert writes a certificate of the common name signed by parent, or self-signed if parent is nil,
+// and its key as PEM files, and returns them.
+func writeTestCert(t *testing.T, dir, name string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key error: %+v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("create certificate error: %+v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key error: %+v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0600); err != nil {
+		t.Fatalf("write certificate error: %+v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600); err != nil {
+		t.Fatalf("write key error: %+v", err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("load certificate error: %+v", err)
+	}
+	cert.Leaf, _ = x509.ParseCertificate(der)
+	return cert
+}
+
+func TestSyslogWriterTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := writeTestCert(t, dir, "ca", nil)
+	server := writeTestCert(t, dir, "server", &ca)
+	writeTestCert(t, dir, "client", &ca)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    roots,
+	})
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer ln.Close()
+
+	// the lines received with the common names of the client certificates
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn *tls.Conn) {
+				defer conn.Close()
+				if conn.Handshake() != nil {
+					return
+				}
+				name := conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- name + " " + scanner.Text()
+				}
+			}(conn.(*tls.Conn))
+		}
+	}()
+
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	w := &SyslogWriter{
+		Address:  "localhost:" + port,
+		Tag:      "app",
+		CertFile: filepath.Join(dir, "client.pem"),
+		KeyFile:  filepath.Join(dir, "client.key"),
+		CAFile:   filepath.Join(dir, "ca.pem"),
+	}
+	defer w.Close()
+
+	receive := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatalf("syslog tls server received nothing")
+			return ""
+		}
+	}
+
+	if _, err := wlprintf(w, InfoLevel, `{"level":"info","message":"hello tls"}`+"\n"); err != nil {
+		t.Fatalf("write syslog tls writer error: %+v", err)
+	}
+	if line := receive(); !strings.HasPrefix(line, "client <6>") || !strings.HasSuffix(line, ` app[`+strconv.Itoa(pid)+`]: {"level":"info","message":"hello tls"}`) {
+		t.Errorf("syslog tls line not correct: %s", line)
+	}
+
+	// rotate the client certificate
+	mtime := now.Add(time.Second)
+	writeTestCert(t, dir, "rotated", &ca)
+	for _, ext := range []string{".pem", ".key"} {
+		if err := os.Rename(filepath.Join(dir, "rotated"+ext), filepath.Join(dir, "client"+ext)); err != nil {
+			t.Fatalf("rotate certificate error: %+v", err)
+		}
+		if err := os.Chtimes(filepath.Join(dir, "client"+ext), mtime, mtime); err != nil {
+			t.Fatalf("chtimes error: %+v", err)
+		}
+	}
+
+	_, _ = wlprintf(w, InfoLevel, `{"level":"info","message":"before reload"}`+"\n")
+	if line := receive(); !strings.HasPrefix(line, "client ") {
+		t.Errorf("syslog tls should not reload before the interval: %s", line)
+	}
+
+	now = now.Add(time.Minute)
+	_, _ = wlprintf(w, InfoLevel, `{"level":"info","message":"after reload"}`+"\n")
+	if line := receive(); !strings.HasPrefix(line, "rotated ") || !strings.Contains(line, "after reload") {
+		t.Errorf("syslog tls should reconnect with the reloaded certificate: %s", line)
+	}
+
+	// an untrusted server
+	w2 := &SyslogWriter{Address: "localhost:" + port, TLSConfig: &tls.Config{}}
+	if _, err := wlprintf(w2, InfoLevel, `{"level":"info","message":"untrusted"}`+"\n"); err == nil {
+		t.Errorf("syslog tls writer should not trust the server")
+	}
+}
+
+func TestSyslogWriterLocal(t *testing.T) {
+	dir := t.TempDir()
+	sockname := filepath.Join(dir, "log")
+	ln, err := net.Listen("unix", sockname)
+	if err != nil {
+		t.Fatalf("listen error: %+v", err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	saved := syslogLocalAddresses
+	syslogLocalAddresses = []string{filepath.Join(dir, "missing"), sockname}
+	defer func() { syslogLocalAddresses = saved }()
+
+	w := &SyslogWriter{Tag: "app"}
+	defer w.Close()
+	if _, err := wlprintf(w, WarnLevel, `{"level":"warn","message":"hello local"}`+"\n"); err != nil {
+		t.Fatalf("write local syslog writer error: %+v", err)
+	}
+	select {
+	case line := <-lines:
+		if !strings.HasPrefix(line, "<4>") || !strings.HasSuffix(line, `{"level":"warn","message":"hello local"}`) {
+			t.Errorf("local syslog line not correct: %s", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("local syslog socket received nothing")
+	}
+
+	syslogLocalAddresses = []string{filepath.Join(dir, "missing")}
+	if _, err := wlprintf(&SyslogWriter{}, InfoLevel, `{"level":"info"}`+"\n"); err == nil {
+		t.Errorf("local syslog writer should fail without a socket")
+	}
+}
@@ -0,0 +1,12 @@
+// Command disabled chains fields and messages on the trace and debug logging
+// eliminated by the logstack_notrace and logstack_nodebug build tags.
+package main
+
+import log "github.com/fabricatorsltd/logstack"
+
+func main() {
+	log.Trace().Str("foo", "bar").Msg("hello from Trace")
+	log.DefaultLogger.Trace().Int("n", 42).Msgf("hello from %s", "Logger.Trace")
+	log.Debug().Str("foo", "bar").Msg("hello from Debug")
+	log.DefaultLogger.Debug().Int("n", 42).Msgf("hello from %s", "Logger.Debug")
+}
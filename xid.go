@@ -25,13 +25,13 @@ func NewXIDWithTime(timestamp int64) (x XID) {
 	x[1] = byte(timestamp >> 16)
 	x[2] = byte(timestamp >> 8)
 	x[3] = byte(timestamp)
-	// machine
-	x[4] = machine[0]
-	x[5] = machine[1]
-	x[6] = machine[2]
-	// pid
-	x[7] = byte(pid >> 8)
-	x[8] = byte(pid)
+	// machine and pid
+	id := atomic.LoadUint64(&xidIdentity)
+	x[4] = byte(id >> 32)
+	x[5] = byte(id >> 24)
+	x[6] = byte(id >> 16)
+	x[7] = byte(id >> 8)
+	x[8] = byte(id)
 	// counter
 	i := atomic.AddUint32(&counter, 1)
 	x[9] = byte(i >> 16)
@@ -45,12 +45,12 @@ func (x XID) Time() time.Time {
 	return time.Unix(int64(x[0])<<32|int64(x[1])<<16|int64(x[2])<<8|int64(x[3]), 0)
 }
 
-// Machine returns the 3-byte machine id part of the id.
+// Machine returns the 3-byte machine id part of the id, see SetXIDIdentity.
 func (x XID) Machine() []byte {
 	return x[4:7]
 }
 
-// Pid returns the process id part of the id.
+// Pid returns the process id part of the id, see SetXIDIdentity.
 func (x XID) Pid() uint16 {
 	return uint16(x[7])<<8 | uint16(x[8])
 }
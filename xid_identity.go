@@ -0,0 +1,144 @@
+package log
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// XIDIdentity is the machine and process part of XIDs, 3 bytes of machine id and 2 bytes of process id.
+type XIDIdentity [5]byte
+
+// XIDSource returns an XIDIdentity, or ErrXIDSourceUnavailable to try the next source of SetXIDIdentity.
+type XIDSource func() (XIDIdentity, error)
+
+// ErrXIDSourceUnavailable is returned by an XIDSource which is not available in the environment.
+var ErrXIDSourceUnavailable = errors.New("log: xid identity source unavailable")
+
+// xidIdentity is the current XIDIdentity packed in the low 40 bits.
+var xidIdentity = packXIDIdentity(defaultXIDIdentity())
+
+func packXIDIdentity(id XIDIdentity) uint64 {
+	return uint64(id[0])<<32 | uint64(id[1])<<24 | uint64(id[2])<<16 | uint64(id[3])<<8 | uint64(id[4])
+}
+
+// defaultXIDIdentity derives the machine id from the hostname and machine-id file, and the process id from the pid.
+// The process id of a container init process is random, as every replica has pid 1.
+func defaultXIDIdentity() (id XIDIdentity) {
+	copy(id[:3], machine[:3])
+	if pid != 1 {
+		id[3], id[4] = byte(pid>>8), byte(pid)
+		return
+	}
+	if _, err := rand.Read(id[3:]); err != nil {
+		i := Fastrandn(1 << 16)
+		id[3], id[4] = byte(i>>8), byte(i)
+	}
+	return
+}
+
+// SetXIDIdentity sets the machine and process part of XIDs from the first available source, it should be called at startup.
+// It returns an error if a source is invalid or no source is available.
+//
+//	id, err := log.SetXIDIdentity(log.XIDFromEnv("XID_IDENTITY"), log.XIDFromPodUID(), log.XIDRandom())
+func SetXIDIdentity(sources ...XIDSource) (XIDIdentity, error) {
+	for _, source := range sources {
+		id, err := source()
+		if err == ErrXIDSourceUnavailable {
+			continue
+		}
+		if err != nil {
+			return id, err
+		}
+		if id == (XIDIdentity{}) {
+			return id, errors.New("log: xid identity is zero")
+		}
+		atomic.StoreUint64(&xidIdentity, packXIDIdentity(id))
+		return id, nil
+	}
+	return XIDIdentity{}, errors.New("log: no xid identity source available")
+}
+
+// hashXIDIdentity derives an XIDIdentity from a seed.
+func hashXIDIdentity(seed []byte) (id XIDIdentity) {
+	sum := md5.Sum(seed)
+	copy(id[:], sum[:])
+	return
+}
+
+// XIDFromBytes is an XIDSource of explicit 5 bytes, 3 bytes of machine id and 2 bytes of process id.
+func XIDFromBytes(b []byte) XIDSource {
+	return func() (id XIDIdentity, err error) {
+		if len(b) != len(id) {
+			return id, errors.New("log: xid identity must be 5 bytes")
+		}
+		copy(id[:], b)
+		return id, nil
+	}
+}
+
+// XIDFromEnv is an XIDSource hashing the value of an environment variable, e.g. a replica name.
+// It is unavailable if the variable is empty.
+func XIDFromEnv(name string) XIDSource {
+	return func() (XIDIdentity, error) {
+		value := getenv(name)
+		if value == "" {
+			return XIDIdentity{}, ErrXIDSourceUnavailable
+		}
+		return hashXIDIdentity([]byte(value)), nil
+	}
+}
+
+// readFile reads /etc/podinfo/uid and /proc/self/cgroup for XIDFromPodUID.
+var readFile = os.ReadFile
+
+var podUIDRegexp = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+
+// XIDFromPodUID is an XIDSource hashing the UID of the Kubernetes pod, from the POD_UID environment variable
+// of the downward API, the /etc/podinfo/uid file of a downward API volume or the cgroup of the process.
+// It is unavailable outside of a pod.
+func XIDFromPodUID() XIDSource {
+	return func() (XIDIdentity, error) {
+		uid := getenv("POD_UID")
+		if uid == "" {
+			if b, err := readFile("/etc/podinfo/uid"); err == nil {
+				uid = string(bytes.TrimSpace(b))
+			}
+		}
+		if uid == "" {
+			if b, err := readFile("/proc/self/cgroup"); err == nil {
+				if m := podUIDRegexp.FindSubmatch(b); m != nil {
+					uid = strings.ReplaceAll(string(m[1]), "_", "-")
+				}
+			}
+		}
+		if uid == "" {
+			return XIDIdentity{}, ErrXIDSourceUnavailable
+		}
+		return hashXIDIdentity([]byte(uid)), nil
+	}
+}
+
+// XIDFromHost is an XIDSource of the default identity, the machine id derived from the hostname
+// and machine-id file, and the pid. It is unavailable for a container init process with pid 1.
+func XIDFromHost() XIDSource {
+	return func() (XIDIdentity, error) {
+		if pid == 1 {
+			return XIDIdentity{}, ErrXIDSourceUnavailable
+		}
+		return defaultXIDIdentity(), nil
+	}
+}
+
+// XIDRandom is an XIDSource of random bytes, unique for every process start.
+func XIDRandom() XIDSource {
+	return func() (id XIDIdentity, err error) {
+		_, err = rand.Read(id[:])
+		return
+	}
+}
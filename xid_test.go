@@ -1,8 +1,11 @@
 package log
 
 import (
+	"bytes"
 	"encoding"
 	"encoding/json"
+	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -127,3 +130,98 @@ func TestXIDMarshalText(t *testing.T) {
 		t.Error("MarshalText()/UnmarshalText mismatched")
 	}
 }
+
+func TestSetXIDIdentity(t *testing.T) {
+	defer func(id uint64) { xidIdentity = id }(xidIdentity)
+	defer func() { getenv, readFile = os.Getenv, os.ReadFile }()
+
+	env := map[string]string{}
+	files := map[string]string{}
+	getenv = func(key string) string { return env[key] }
+	readFile = func(name string) ([]byte, error) {
+		if s, ok := files[name]; ok {
+			return []byte(s), nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	id, err := SetXIDIdentity(XIDFromBytes([]byte{1, 2, 3, 4, 5}))
+	if err != nil || id != (XIDIdentity{1, 2, 3, 4, 5}) {
+		t.Fatalf("SetXIDIdentity bytes not correct: %v %+v", id, err)
+	}
+	x := NewXID()
+	if !bytes.Equal(x.Machine(), []byte{1, 2, 3}) || x.Pid() != 0x0405 {
+		t.Errorf("XID identity not correct: %v %d", x.Machine(), x.Pid())
+	}
+
+	// unavailable sources are skipped
+	files["/proc/self/cgroup"] = "0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod3f9a2b1c_7d4e_4f5a_9b8c_1d2e3f4a5b6c.slice/cri-containerd-abc.scope\n"
+	id, err = SetXIDIdentity(XIDFromEnv("XID_IDENTITY"), XIDFromPodUID(), XIDRandom())
+	if err != nil || id != hashXIDIdentity([]byte("3f9a2b1c-7d4e-4f5a-9b8c-1d2e3f4a5b6c")) {
+		t.Errorf("SetXIDIdentity pod cgroup not correct: %v %+v", id, err)
+	}
+	files["/etc/podinfo/uid"] = "3f9a2b1c-7d4e-4f5a-9b8c-1d2e3f4a5b6c\n"
+	if id2, _ := SetXIDIdentity(XIDFromPodUID()); id2 != id {
+		t.Errorf("SetXIDIdentity podinfo not correct: %v", id2)
+	}
+
+	env["XID_IDENTITY"] = "replica-1"
+	id, err = SetXIDIdentity(XIDFromEnv("XID_IDENTITY"), XIDFromPodUID())
+	if err != nil || id != hashXIDIdentity([]byte("replica-1")) {
+		t.Errorf("SetXIDIdentity env not correct: %v %+v", id, err)
+	}
+	if x := NewXID(); !bytes.Equal(x.Machine(), id[:3]) || x.Pid() != uint16(id[3])<<8|uint16(id[4]) {
+		t.Errorf("XID identity not correct: %v %d", x.Machine(), x.Pid())
+	}
+
+	for _, sources := range [][]XIDSource{
+		nil,
+		{XIDFromEnv("NOT_SET")},
+		{XIDFromBytes([]byte{1, 2, 3})},
+		{XIDFromBytes([]byte{0, 0, 0, 0, 0})},
+	} {
+		if _, err := SetXIDIdentity(sources...); err == nil {
+			t.Errorf("SetXIDIdentity(%d sources) should return an error", len(sources))
+		}
+	}
+	if x := NewXID(); !bytes.Equal(x.Machine(), id[:3]) {
+		t.Errorf("invalid SetXIDIdentity should keep the identity: %v", x.Machine())
+	}
+
+	a, _ := XIDRandom()()
+	b, _ := XIDRandom()()
+	if a == b {
+		t.Errorf("XIDRandom should be random: %v %v", a, b)
+	}
+}
+
+func TestLocalHostname(t *testing.T) {
+	defer func() { getenv, readFile = os.Getenv, os.ReadFile }()
+
+	// two instances of the same image share the machine-id, and run as pid 1
+	env := map[string]string{}
+	getenv = func(key string) string { return env[key] }
+	readFile = func(name string) ([]byte, error) {
+		if name == "/etc/machine-id" {
+			return []byte("0123456789abcdef0123456789abcdef\n"), nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	if host := localHostname("shire", nil); host != "shire" {
+		t.Errorf("localHostname should keep the hostname: %s", host)
+	}
+
+	a, b := localHostname("localhost", nil), localHostname("", os.ErrNotExist)
+	if a == b || !strings.HasPrefix(a, "localhost-") || !strings.HasPrefix(b, "localhost-") {
+		t.Errorf("localHostname of instances sharing a machine-id should differ: %s %s", a, b)
+	}
+
+	// the pod UID is stable across restarts of a replica
+	env["POD_UID"] = "3f9a2b1c-7d4e-4f5a-9b8c-1d2e3f4a5b6c"
+	a, b = localHostname("localhost", nil), localHostname("localhost", nil)
+	env["POD_UID"] = "5b6c1d2e-3f4a-4f5a-9b8c-3f9a2b1c7d4e"
+	if c := localHostname("localhost", nil); a != b || a == c {
+		t.Errorf("localHostname of pods not correct: %s %s %s", a, b, c)
+	}
+}